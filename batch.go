@@ -0,0 +1,155 @@
+package battcrypt
+
+import (
+	"encoding/binary"
+
+	"github.com/BenLubar/battcrypt/blowfish"
+)
+
+// A Job is one hash computed by BATTCryptBatch.
+type Job struct {
+	Password, Salt []byte
+	Params         Params
+
+	// Key and Err are set by BATTCryptBatch to the results BATTCrypt
+	// would have returned.
+	Key [64]byte
+	Err error
+}
+
+// batchSize is the most jobs BATTCryptBatch computes in lockstep.
+const batchSize = 4
+
+// BATTCryptBatch computes the hash of every job, giving the same results as
+// calling BATTCrypt for each of them. Jobs with the same Params are computed
+// in groups of up to four in a single goroutine, with their Blowfish
+// encryptions interleaved, which keeps the CPU busier than computing them
+// one at a time. Jobs with more than one lane, and groups that would go over
+// the memory limit, are computed one at a time instead.
+func BATTCryptBatch(jobs []Job) {
+	var order []Params
+	groups := make(map[Params][]*Job)
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Params.Lanes > 1 || job.Params.Validate() != nil {
+			job.Key, job.Err = BATTCryptWithOptions(job.Password, job.Salt, job.Params, nil)
+			continue
+		}
+		if _, ok := groups[job.Params]; !ok {
+			order = append(order, job.Params)
+		}
+		groups[job.Params] = append(groups[job.Params], job)
+	}
+
+	for _, p := range order {
+		group := groups[p]
+		for len(group) > batchSize {
+			hashBatch(group[:batchSize], p)
+			group = group[batchSize:]
+		}
+		hashBatch(group, p)
+	}
+}
+
+// hashBatch computes jobs in lockstep. p has already been checked and has a
+// single lane.
+func hashBatch(jobs []*Job, p Params) {
+	if len(jobs) == 1 {
+		jobs[0].Key, jobs[0].Err = BATTCryptWithOptions(jobs[0].Password, jobs[0].Salt, p, nil)
+		return
+	}
+
+	hs := make([]*Hasher, 0, len(jobs))
+	release := func() {
+		for _, h := range hs {
+			h.end()
+			putHasher(p.Memory, h)
+		}
+	}
+	for range jobs {
+		h := getHasher(p.Memory, nil)
+		if err := h.begin(p, nil); err != nil {
+			putHasher(p.Memory, h)
+			release()
+			for _, job := range jobs {
+				job.Key, job.Err = BATTCryptWithOptions(job.Password, job.Salt, p, nil)
+			}
+			return
+		}
+		hs = append(hs, h)
+	}
+	defer release()
+
+	for i, job := range jobs {
+		hs[i].lane.deriveKey(job.Password, job.Salt, nil)
+	}
+	streams := make([]blowfish.CBCStream, len(hs))
+	for u := p.UpgradeIterations(); u > 0; u-- {
+		batchRound(hs, streams)
+	}
+	for i, job := range jobs {
+		job.Key, job.Err = hs[i].key, nil
+	}
+}
+
+// batchRound is battcrypt for the single lane of each of hs, computed in
+// lockstep. All of hs must be computing hashes with the same costs.
+func batchRound(hs []*Hasher, streams []blowfish.CBCStream) {
+	t_cost_main, mem_size, mode := hs[0].t_cost_main, hs[0].seg_size, hs[0].mode
+
+	// Initialize blowfish and data
+	for k, h := range hs {
+		if err := h.blow.Reset(h.key[:56]); err != nil {
+			// only possible error is invalid key size
+			panic(err)
+		}
+		streams[k] = blowfish.CBCStream{Cipher: h.blow}
+		h.lane.initData()
+	}
+
+	// Initialize mem
+	for i := uint64(0); i < mem_size; i++ {
+		for k, h := range hs {
+			streams[k].Dst, streams[k].Src = h.data, h.data
+		}
+		blowfish.EncryptCBCInterleaved(streams)
+		for _, h := range hs {
+			copy(h.mem[i], h.data)
+		}
+	}
+	for k, h := range hs {
+		streams[k].Dst, streams[k].Src = h.data, h.data
+	}
+	blowfish.EncryptCBCInterleaved(streams)
+
+	// Main loop
+	for i := uint64(0); i < t_cost_main; i++ {
+		independent := mode == ModeIndependent || (mode == ModeHybrid && i == 0)
+		for j := uint64(0); j < mem_size; j++ {
+			if independent && j%8 == 0 {
+				// The indices depend only on the costs, so they
+				// are the same for every job.
+				hs[0].lane.nextIndices(mem_size, t_cost_main, i, j/8)
+			}
+			for k, h := range hs {
+				var r uint64
+				if independent {
+					r = binary.BigEndian.Uint64(hs[0].indices[j%8*8:]) & (mem_size - 1)
+				} else {
+					r = binary.BigEndian.Uint64(h.data[size-8:]) & (mem_size - 1)
+				}
+				fast_xor(h.mem[j], h.mem[j], h.mem[r])
+				fast_xor(h.mem[j], h.mem[j], h.data)
+				streams[k].Dst, streams[k].Src = h.mem[j], h.mem[j]
+			}
+			blowfish.EncryptCBCInterleaved(streams)
+			for _, h := range hs {
+				fast_xor(h.data, h.data, h.mem[j])
+			}
+		}
+	}
+
+	for _, h := range hs {
+		h.lane.finish()
+	}
+}
@@ -0,0 +1,83 @@
+package battcrypt
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBATTCryptBatch(t *testing.T) {
+	var jobs []Job
+	for i, p := range []Params{
+		{Time: 1, Upgrade: 1, Memory: 2},
+		{Time: 0, Upgrade: 0, Memory: 0},
+		{Time: 1, Upgrade: 1, Memory: 2, Mode: ModeHybrid},
+		{Time: 0, Upgrade: 2, Memory: 1, Mode: ModeIndependent},
+		{Time: 0, Upgrade: 0, Memory: 2, Lanes: 2},
+		{Time: MaxTime + 1},
+	} {
+		// Enough jobs with each of the costs for a full group and
+		// part of another.
+		for j := 0; j <= batchSize+i%3; j++ {
+			jobs = append(jobs, Job{
+				Password: []byte("password " + strconv.Itoa(j)),
+				Salt:     []byte("salt " + strconv.Itoa(i)),
+				Params:   p,
+			})
+		}
+	}
+
+	BATTCryptBatch(jobs)
+
+	for _, job := range jobs {
+		key, err := BATTCryptWithOptions(job.Password, job.Salt, job.Params, nil)
+		if job.Key != key || job.Err != err {
+			t.Errorf("%q %q %v: %x, %v; expected %x, %v", job.Password, job.Salt, job.Params, job.Key, job.Err, key, err)
+		}
+	}
+}
+
+func TestBATTCryptBatchMemoryLimit(t *testing.T) {
+	p := Params{Time: 0, Upgrade: 0, Memory: 2}
+	// Room for one job at a time, but not a group.
+	defer SetMemoryLimit(SetMemoryLimit(p.MemoryBytes()))
+
+	jobs := []Job{{Password: []byte("a"), Params: p}, {Password: []byte("b"), Params: p}}
+	BATTCryptBatch(jobs)
+	for _, job := range jobs {
+		key, err := BATTCryptWithOptions(job.Password, nil, p, nil)
+		if job.Key != key || job.Err != nil || err != nil {
+			t.Errorf("%q: %x, %v; expected %x, %v", job.Password, job.Key, job.Err, key, err)
+		}
+	}
+}
+
+func batchBenchmarkJobs(n int) []Job {
+	jobs := make([]Job, n)
+	for i := range jobs {
+		jobs[i] = Job{Password: xkcd, Salt: []byte{byte(i)}, Params: Params{Time: 1, Upgrade: 0, Memory: 4}}
+	}
+	return jobs
+}
+
+func doBatchBenchmark(b *testing.B, n int) {
+	jobs := batchBenchmarkJobs(n)
+	for i := 0; i < b.N; i++ {
+		BATTCryptBatch(jobs)
+	}
+}
+
+func doSingleBenchmark(b *testing.B, n int) {
+	jobs := batchBenchmarkJobs(n)
+	for i := 0; i < b.N; i++ {
+		for j := range jobs {
+			jobs[j].Key, jobs[j].Err = BATTCryptWithOptions(jobs[j].Password, jobs[j].Salt, jobs[j].Params, nil)
+		}
+	}
+}
+
+func BenchmarkBatch2(b *testing.B)  { doBatchBenchmark(b, 2) }
+func BenchmarkBatch4(b *testing.B)  { doBatchBenchmark(b, 4) }
+func BenchmarkBatch8(b *testing.B)  { doBatchBenchmark(b, 8) }
+func BenchmarkSingle2(b *testing.B) { doSingleBenchmark(b, 2) }
+func BenchmarkSingle4(b *testing.B) { doSingleBenchmark(b, 4) }
+func BenchmarkSingle8(b *testing.B) { doSingleBenchmark(b, 8) }
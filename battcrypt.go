@@ -12,18 +12,22 @@
 // If time and memory stay the same, upgrade can be increased without input
 // from the user.
 //
+// Params.Mode selects how memory is addressed. The original battcrypt
+// algorithm, ModeDependent, is the default; ModeIndependent and ModeHybrid
+// protect against attackers who can observe cache timing. Params.Lanes lets
+// a single hash use more than one core.
+//
 // For comparable complexity to bcrypt, set time to 1, upgrade to 0, and memory
-// to bcrypt_cost - 2.
+// to bcrypt_cost - 2. Calibrate can choose costs that suit the current
+// machine instead.
 //
 package battcrypt
 
 import (
-	"crypto/cipher"
+	"context"
 	"crypto/sha512"
 	"encoding/binary"
 	"errors"
-	"hash"
-	"sync"
 	"unsafe"
 
 	"github.com/BenLubar/battcrypt/blowfish"
@@ -59,114 +63,298 @@ func costs(t_cost_1, t_cost_2, m_cost uint64) (t_cost_main, t_cost_upgrade, mem_
 	return
 }
 
-var blowPool = sync.Pool{
-	New: func() interface{} {
-		blow, err := blowfish.NewCipher(emptyKey)
-		if err != nil {
-			// only possible error is invalid key size
-			panic(err)
-		}
-		return blow
-	},
+// checkInterval is the number of blocks processed between checks for
+// cancellation and progress reports.
+const checkInterval = 64
+
+// progressSteps is the most times Options.Progress is called for one hash,
+// not counting the final call.
+const progressSteps = 1000
+
+// Options holds optional settings for BATTCryptWithOptions and
+// StrengthenWithOptions. A nil *Options is the same as the zero Options.
+type Options struct {
+	// Context, if not nil, is checked periodically while hashing. If it is
+	// done, the partial work is wiped and Context.Err() is returned.
+	Context context.Context
+
+	// Progress, if not nil, is called from the hashing goroutine as work is
+	// completed. A unit of work is one pass of Blowfish over a 2 KiB
+	// block, and total counts every upgrade iteration that will be
+	// performed. Progress is called at most a little over 1000 times per
+	// hash, and always once with completed equal to total on success.
+	Progress func(completed, total uint64)
+
+	// AssociatedData, if not empty, binds the hash to a context such as a
+	// username or application name. The same password, salt, and costs
+	// give unrelated hashes for different associated data, and the same
+	// associated data must be given again to reproduce a hash.
+	AssociatedData []byte
+
+	// MemoryLimit, if not 0, is the most memory in bytes this hash may
+	// use. It is checked in addition to the limit set by SetMemoryLimit.
+	MemoryLimit uint64
+
+	// LockMemory, if set, keeps the working memory out of swap and core
+	// dumps where the system allows it. See NewLockedHasher.
+	LockMemory bool
+
+	// ConstantTime, if set, uses the constant-time Blowfish implementation
+	// (see blowfish.Cipher.SetConstantTime), so that the key schedules and
+	// blocks do not leak through cache timing. It is many times slower, and
+	// the hash is the same either way. It does not hide which blocks of
+	// memory are read, which depends on the password in ModeDependent.
+	ConstantTime bool
+}
+
+func (opts *Options) context() context.Context {
+	if opts == nil || opts.Context == nil {
+		return context.Background()
+	}
+	return opts.Context
+}
+
+func (opts *Options) progress() func(completed, total uint64) {
+	if opts == nil {
+		return nil
+	}
+	return opts.Progress
+}
+
+func (opts *Options) memoryLimit() uint64 {
+	if opts == nil {
+		return 0
+	}
+	return opts.MemoryLimit
+}
+
+func (opts *Options) lockMemory() bool {
+	return opts != nil && opts.LockMemory
+}
+
+func (opts *Options) constantTime() bool {
+	return opts != nil && opts.ConstantTime
+}
+
+func (opts *Options) associatedData() []byte {
+	if opts == nil {
+		return nil
+	}
+	return opts.AssociatedData
 }
 
 // BATTCrypt computes a cryptographic hash of password, salted by salt.
 func BATTCrypt(password, salt []byte, time, upgrade, memory uint64) (key [64]byte, err error) {
-	t_cost_main, t_cost_upgrade, mem_size, err := costs(time, upgrade, memory)
-	if err != nil {
-		return
-	}
+	return BATTCryptWithOptions(password, salt, Params{Time: time, Upgrade: upgrade, Memory: memory}, nil)
+}
 
-	sha := sha512.New()
-	blow := blowPool.Get().(*blowfish.Cipher)
-	defer blowPool.Put(blow)
+// BATTCryptContext is like BATTCrypt, but gives up and returns ctx.Err() if
+// ctx is done before the hash is finished.
+func BATTCryptContext(ctx context.Context, password, salt []byte, time, upgrade, memory uint64) (key [64]byte, err error) {
+	return BATTCryptWithOptions(password, salt, Params{Time: time, Upgrade: upgrade, Memory: memory}, &Options{Context: ctx})
+}
 
-	data := make([]byte, size*(mem_size+1))
-	mem := make([][]byte, mem_size)
-	for i := range mem {
-		mem[i] = data[:size:size]
-		data = data[size:]
+// BATTCryptWithOptions is like BATTCrypt, but takes its costs as Params and
+// accepts optional settings.
+func BATTCryptWithOptions(password, salt []byte, p Params, opts *Options) (key [64]byte, err error) {
+	return hashPassword(password, salt, p, 0, opts)
+}
+
+// hashPassword is BATTCryptWithOptions with extra upgrade iterations added
+// at the end, as done by the Strengthen function of earlier releases.
+func hashPassword(password, salt []byte, p Params, extra uint64, opts *Options) ([64]byte, error) {
+	if err := p.Validate(); err != nil {
+		return [64]byte{}, err
 	}
 
-	sha.Reset()
-	sha.Write(salt)
-	sha.Sum(key[:0])
+	h := getHasher(p.Memory, opts)
+	defer putHasher(p.Memory, h)
 
-	sha.Reset()
-	sha.Write(key[:])
-	sha.Write(password)
-	sha.Sum(key[:0])
+	return h.hashPassword(password, salt, p, extra, opts)
+}
 
-	for u := uint64(0); u < t_cost_upgrade; u++ {
-		key = battcrypt(key, sha, blow, data, mem, t_cost_main, mem_size)
+// matchLegacy reports whether password and salt give want with costs p and
+// up to maxExtra extra upgrade iterations.
+func matchLegacy(password, salt []byte, p Params, want [64]byte, maxExtra uint64, opts *Options) (bool, error) {
+	if err := p.Validate(); err != nil {
+		return false, err
 	}
 
-	return
+	h := getHasher(p.Memory, opts)
+	defer putHasher(p.Memory, h)
+
+	return h.matchLegacy(password, salt, p, want, maxExtra, opts)
 }
 
 var emptyKey = make([]byte, 56)
 var emptyIV = make([]byte, blowfish.BlockSize)
 
-func battcrypt(key [64]byte, sha hash.Hash, blow *blowfish.Cipher, data []byte, mem [][]byte, t_cost_main, mem_size uint64) [64]byte {
-	var scratch [8]byte
+// canceled reports whether done has been closed. A nil done is never closed.
+func canceled(done <-chan struct{}) bool {
+	if done == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// battcrypt performs one upgrade iteration on l.key, using l's segment of
+// h's memory. Progress is reported if reports is set. It returns false
+// without finishing if the computation is canceled while it is running, or
+// another lane gives up.
+func (h *Hasher) battcrypt(l *lane, reports bool) bool {
+	blow, cbc, fused, data, mem := l.blow, l.cbc, l.fused, l.data, l.mem
+	t_cost_main, mem_size, mode := h.t_cost_main, h.seg_size, h.mode
+	lanes := uint64(len(h.blocks)) / mem_size
 
 	// Initialize blowfish
-	err := blow.Reset(key[:56])
+	err := blow.Reset(l.key[:56])
 	if err != nil {
 		// only possible error is invalid key size
 		panic(err)
 	}
-	cbc := cipher.NewCBCEncrypter(blow, emptyIV)
+	cbc.(ivSetter).SetIV(emptyIV)
 
-	// Initialize data
-	data = data[:0]
-	for i := uint64(0); i < 32; i++ {
-		sha.Reset()
-		binary.BigEndian.PutUint64(scratch[:8], i)
-		sha.Write(scratch[:8])
-		sha.Write(key[:])
-		data = sha.Sum(data)
-	}
+	l.initData()
 
 	// Initialize mem
 	for i := uint64(0); i < mem_size; i++ {
 		cbc.CryptBlocks(data, data)
 		copy(mem[i], data)
+		if !h.tick(l, reports) {
+			return false
+		}
 	}
 	cbc.CryptBlocks(data, data)
+	if lanes > 1 && !h.barrier.wait() {
+		return false
+	}
 
 	// Main loop
 	for i := uint64(0); i < t_cost_main; i++ {
+		independent := mode == ModeIndependent || (mode == ModeHybrid && i == 0)
 		for j := uint64(0); j < mem_size; j++ {
-			r := binary.BigEndian.Uint64(data[size-8:]) & (mem_size - 1)
-			fast_xor(mem[j], mem[j], mem[r])
-			fast_xor(mem[j], mem[j], data)
-			cbc.CryptBlocks(mem[j], mem[j])
-			fast_xor(data, data, mem[j])
+			var r uint64
+			if independent {
+				if j%8 == 0 {
+					l.nextIndices(mem_size, t_cost_main, i, j/8)
+				}
+				r = binary.BigEndian.Uint64(l.indices[j%8*8:])
+			} else {
+				r = binary.BigEndian.Uint64(data[size-8:])
+			}
+			src := mem[r&(mem_size-1)]
+			if lanes > 1 {
+				src = h.blocks[refLane(r, j, l.index, lanes, mem_size)*mem_size+r&(mem_size-1)]
+			}
+			if fused != nil {
+				fused.CryptBlocksXOR(mem[j], src, data)
+			} else {
+				fast_xor(mem[j], mem[j], src)
+				fast_xor(mem[j], mem[j], data)
+				cbc.CryptBlocks(mem[j], mem[j])
+				fast_xor(data, data, mem[j])
+			}
+			if !h.tick(l, reports) {
+				return false
+			}
+			if lanes > 1 && (j+1)%(mem_size/slices) == 0 && !h.barrier.wait() {
+				return false
+			}
 		}
 	}
+	if !h.flush(l, reports) {
+		return false
+	}
+
+	l.finish()
+	return true
+}
+
+// refLane returns the lane whose block r&(mem_size-1) is mixed into block j
+// of lane self, where mem_size is the number of blocks in each lane. The top
+// bits of r pick the lane. Blocks of other lanes in the slice being filled
+// are still changing, so the lane's own block is used for them instead.
+func refLane(r, j, self, lanes, mem_size uint64) uint64 {
+	slice := mem_size/slices - 1 // the bits of an index within a slice
+	if r&(mem_size-1)&^slice == j&^slice {
+		return self
+	}
+	return r >> 57 & (lanes - 1)
+}
+
+// initData fills l.data from l.key at the start of an upgrade iteration.
+func (l *lane) initData() {
+	sha, data, key, scratch := l.sha, l.data[:0], l.key[:], l.scratch[:]
+	for i := uint64(0); i < 32; i++ {
+		sha.Reset()
+		binary.BigEndian.PutUint64(scratch, i)
+		sha.Write(scratch)
+		sha.Write(key)
+		data = sha.Sum(data)
+	}
+}
+
+// finish replaces l.key with the result of an upgrade iteration.
+func (l *lane) finish() {
+	sha, key := l.sha, l.key[:]
 
-	// Finish
 	sha.Reset()
-	sha.Write(data)
-	sha.Write(key[:])
+	sha.Write(l.data)
+	sha.Write(key)
 	sha.Sum(key[:0])
 
 	sha.Reset()
-	sha.Write(key[:])
+	sha.Write(key)
 	sha.Sum(key[:0])
+}
 
-	return key
+// nextIndices fills l.indices with the block indices for blocks 8*counter
+// through 8*counter+7 of the given pass, for the data-independent modes. They
+// are taken from SHA-512 of the number of memory blocks in the lane, the
+// number of passes, the pass, and the counter, so they do not depend on the
+// password or salt.
+func (l *lane) nextIndices(mem_size, t_cost_main, pass, counter uint64) {
+	sha, scratch := l.sha, l.scratch[:]
+
+	sha.Reset()
+	for _, n := range [...]uint64{mem_size, t_cost_main, pass, counter} {
+		binary.BigEndian.PutUint64(scratch, n)
+		sha.Write(scratch)
+	}
+	sha.Sum(l.indices[:0])
 }
 
 // Strengthen can be used to increase the time complexity of a password hash
-// without needing input from the user.
+// without needing input from the user. The result is the same as if the hash
+// had been computed with upgrade_new in the first place.
+//
+// Earlier releases of this package performed one upgrade iteration too many
+// each time Strengthen was called. Encoded hashes from those releases have
+// version 0, and are still accepted by CompareHashAndPassword; see
+// Hash.ExtraIterations.
 func Strengthen(old [64]byte, time, upgrade_old, upgrade_new, memory uint64) (key [64]byte, err error) {
-	t_cost_main, t_cost_upgrade_old, mem_size, err := costs(time, upgrade_old, memory)
+	return StrengthenWithOptions(old, Params{Time: time, Upgrade: upgrade_old, Memory: memory}, upgrade_new, nil)
+}
+
+// StrengthenContext is like Strengthen, but gives up and returns ctx.Err() if
+// ctx is done before the hash is finished.
+func StrengthenContext(ctx context.Context, old [64]byte, time, upgrade_old, upgrade_new, memory uint64) (key [64]byte, err error) {
+	return StrengthenWithOptions(old, Params{Time: time, Upgrade: upgrade_old, Memory: memory}, upgrade_new, &Options{Context: ctx})
+}
+
+// StrengthenWithOptions is like Strengthen, but takes the old costs as
+// Params and accepts optional settings.
+func StrengthenWithOptions(old [64]byte, p Params, upgrade_new uint64, opts *Options) (key [64]byte, err error) {
+	_, t_cost_upgrade_old, _, _, err := p.layout()
 	if err != nil {
 		return
 	}
-	_, t_cost_upgrade_new, _, err := costs(time, upgrade_new, memory)
+	_, t_cost_upgrade_new, _, err := costs(p.Time, upgrade_new, p.Memory)
 	if err != nil {
 		return
 	}
@@ -175,27 +363,14 @@ func Strengthen(old [64]byte, time, upgrade_old, upgrade_new, memory uint64) (ke
 		err = ErrUpgradeInvalid
 		return
 	}
-	key = old
 	if t_cost_upgrade_old == t_cost_upgrade_new {
-		return
+		return old, nil
 	}
 
-	sha := sha512.New()
-	blow := blowPool.Get().(*blowfish.Cipher)
-	defer blowPool.Put(blow)
+	h := getHasher(p.Memory, opts)
+	defer putHasher(p.Memory, h)
 
-	data := make([]byte, size*(mem_size+1))
-	mem := make([][]byte, mem_size)
-	for i := range mem {
-		mem[i] = data[:size:size]
-		data = data[size:]
-	}
-
-	for u := t_cost_upgrade_old - 1; u < t_cost_upgrade_new; u++ {
-		key = battcrypt(key, sha, blow, data, mem, t_cost_main, mem_size)
-	}
-
-	return
+	return h.strengthen(old, p, t_cost_upgrade_new-t_cost_upgrade_old, opts)
 }
 
 // dst, x, and y must all be exactly size len.
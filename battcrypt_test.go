@@ -78,6 +78,73 @@ func TestHashes(t *testing.T) {
 		if hash != test.Hash {
 			t.Errorf("%q != %q for %#v", hash, test.Hash, test)
 		}
+
+		// Empty associated data must not change the result.
+		for _, ad := range [][]byte{nil, {}} {
+			key, err = BATTCryptWithOptions(password, salt, Params{Time: test.Time, Upgrade: test.Upgrade, Memory: test.Mem}, &Options{AssociatedData: ad})
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if hash := hex.EncodeToString(key[:]); hash != test.Hash {
+				t.Errorf("%q != %q for %#v with associated data %#v", hash, test.Hash, test, ad)
+			}
+		}
+	}
+}
+
+func TestAssociatedData(t *testing.T) {
+	hash := func(salt, ad string) [64]byte {
+		key, err := BATTCryptWithOptions([]byte("password"), []byte(salt), Params{Time: 1, Upgrade: 1, Memory: 1}, &Options{AssociatedData: []byte(ad)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	key := hash("salt", "alice@example.com")
+	if expected := "f8342eceaec83cff0fd3b54e0f16aad53281bee96f3f3cef9d082f3079ccdbc3d89b9204d96069c60ed6d09250748cc451e9aa52f3f2d49b19672023bd0d34f7"; hex.EncodeToString(key[:]) != expected {
+		t.Errorf("%x != %s", key, expected)
+	}
+	if key == hash("salt", "bob@example.com") {
+		t.Error("different associated data gave the same hash")
+	}
+	if hash("saltal", "ice@example.com") == key || hash("salt", "") == hash("", "salt") {
+		t.Error("salt and associated data are ambiguous")
+	}
+
+	encoded, err := GenerateFromPasswordWithOptions(xkcd, Params{}, &Options{AssociatedData: []byte("alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPasswordWithOptions(encoded, xkcd, &Options{AssociatedData: []byte("alice")}); err != nil {
+		t.Error(err)
+	}
+	if err := CompareHashAndPasswordWithOptions(encoded, xkcd, &Options{AssociatedData: []byte("bob")}); err != ErrMismatchedHashAndPassword {
+		t.Errorf("hash copied to another account: %v", err)
+	}
+	if err := CompareHashAndPassword(encoded, xkcd); err != ErrMismatchedHashAndPassword {
+		t.Errorf("associated data omitted: %v", err)
+	}
+}
+
+func TestConstantTime(t *testing.T) {
+	h := NewHasher()
+	for _, p := range []Params{{Time: 1, Upgrade: 0, Memory: 0}, {Time: 0, Upgrade: 0, Memory: 1, Lanes: 2}} {
+		expected, err := BATTCryptWithOptions(xkcd, salt, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The same Hasher must switch back and forth.
+		for _, ct := range []bool{true, false, true} {
+			key, err := h.BATTCrypt(xkcd, salt, p, &Options{ConstantTime: ct})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != expected {
+				t.Errorf("%v constant time %v: %x != %x", p, ct, key, expected)
+			}
+		}
 	}
 }
 
@@ -85,6 +152,7 @@ var xkcd = []byte("correct horse battery staple")
 var salt = []byte("")
 
 func doBenchmark(b *testing.B, time, upgrade, memory uint64) {
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		_, _ = BATTCrypt(xkcd, salt, time, upgrade, memory)
 	}
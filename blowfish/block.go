@@ -42,24 +42,24 @@ func ExpandKey(key []byte, c *Cipher) {
 
 	var l, r uint32
 	for i := 0; i < 18; i += 2 {
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.p[i], c.p[i+1] = l, r
 	}
 
 	for i := 0; i < 256; i += 2 {
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s0[i], c.s0[i+1] = l, r
 	}
 	for i := 0; i < 256; i += 2 {
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s1[i], c.s1[i+1] = l, r
 	}
 	for i := 0; i < 256; i += 2 {
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s2[i], c.s2[i+1] = l, r
 	}
 	for i := 0; i < 256; i += 2 {
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s3[i], c.s3[i+1] = l, r
 	}
 }
@@ -79,40 +79,40 @@ func expandKeyWithSalt(key []byte, salt []byte, c *Cipher) {
 	for i := 0; i < 18; i += 2 {
 		l ^= getNextWord(salt, &j)
 		r ^= getNextWord(salt, &j)
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.p[i], c.p[i+1] = l, r
 	}
 
 	for i := 0; i < 256; i += 2 {
 		l ^= getNextWord(salt, &j)
 		r ^= getNextWord(salt, &j)
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s0[i], c.s0[i+1] = l, r
 	}
 
 	for i := 0; i < 256; i += 2 {
 		l ^= getNextWord(salt, &j)
 		r ^= getNextWord(salt, &j)
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s1[i], c.s1[i+1] = l, r
 	}
 
 	for i := 0; i < 256; i += 2 {
 		l ^= getNextWord(salt, &j)
 		r ^= getNextWord(salt, &j)
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s2[i], c.s2[i+1] = l, r
 	}
 
 	for i := 0; i < 256; i += 2 {
 		l ^= getNextWord(salt, &j)
 		r ^= getNextWord(salt, &j)
-		l, r = encryptBlock(l, r, c)
+		l, r = c.encrypt(l, r)
 		c.s3[i], c.s3[i+1] = l, r
 	}
 }
 
-func encryptBlock(l, r uint32, c *Cipher) (uint32, uint32) {
+func encryptBlockGeneric(l, r uint32, c *Cipher) (uint32, uint32) {
 	xl, xr := l, r
 	xl ^= c.p[0]
 	xr ^= ((c.s0[byte(xl>>24)] + c.s1[byte(xl>>16)]) ^ c.s2[byte(xl>>8)]) + c.s3[byte(xl)] ^ c.p[1]
@@ -135,7 +135,7 @@ func encryptBlock(l, r uint32, c *Cipher) (uint32, uint32) {
 	return xr, xl
 }
 
-func decryptBlock(l, r uint32, c *Cipher) (uint32, uint32) {
+func decryptBlockGeneric(l, r uint32, c *Cipher) (uint32, uint32) {
 	xl, xr := l, r
 	xl ^= c.p[17]
 	xr ^= ((c.s0[byte(xl>>24)] + c.s1[byte(xl>>16)]) ^ c.s2[byte(xl>>8)]) + c.s3[byte(xl)] ^ c.p[16]
@@ -0,0 +1,27 @@
+//go:build amd64 && !purego
+
+package blowfish
+
+// These are implemented in block_amd64.s, and each mirrors the Go function
+// of the same name with Generic added. Encrypting a block is a chain of
+// dependent S-box lookups that the assembly cannot shorten, so encryptBlock
+// and encryptBlocksCBC run at about the speed of the Go code. The blocks
+// given to decryptBlocksCBC do not depend on each other, so it is faster.
+
+//go:noescape
+func encryptBlock(l, r uint32, c *Cipher) (rl, rr uint32)
+
+//go:noescape
+func decryptBlock(l, r uint32, c *Cipher) (rl, rr uint32)
+
+// encryptBlocksCBC encrypts src into dst in CBC mode, starting from the
+// chaining value l, r, and returns the last ciphertext block. len(src) must
+// be a multiple of BlockSize, and dst must be at least as long.
+//
+//go:noescape
+func encryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (rl, rr uint32)
+
+// decryptBlocksCBC is the inverse of encryptBlocksCBC.
+//
+//go:noescape
+func decryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (rl, rr uint32)
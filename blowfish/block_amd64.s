@@ -0,0 +1,1074 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Offsets into Cipher: p is at 0, and s0 through s3 follow it at 72, 1096,
+// 2120, and 3144. Each round is written out in full, with CX and DX as
+// scratch registers.
+
+// encryptBlock mirrors encryptBlockGeneric in block.go.
+//
+// func encryptBlock(l, r uint32, c *Cipher) (rl, rr uint32)
+TEXT ·encryptBlock(SB), NOSPLIT, $0-24
+	MOVL l+0(FP), AX
+	MOVL r+4(FP), BX
+	MOVQ c+8(FP), SI
+	XORL 0(SI), AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 4(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 8(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 12(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 16(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 20(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 24(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 28(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 32(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 36(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 40(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 44(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 48(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 52(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 56(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 60(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 64(SI), DX
+	XORL DX, AX
+	XORL 68(SI), BX
+	MOVL BX, rl+16(FP)
+	MOVL AX, rr+20(FP)
+	RET
+
+// decryptBlock mirrors decryptBlockGeneric in block.go.
+//
+// func decryptBlock(l, r uint32, c *Cipher) (rl, rr uint32)
+TEXT ·decryptBlock(SB), NOSPLIT, $0-24
+	MOVL l+0(FP), AX
+	MOVL r+4(FP), BX
+	MOVQ c+8(FP), SI
+	XORL 68(SI), AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 64(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 60(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 56(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 52(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 48(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 44(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 40(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 36(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 32(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 28(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 24(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 20(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 16(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 12(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 8(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 4(SI), DX
+	XORL DX, AX
+	XORL 0(SI), BX
+	MOVL BX, rl+16(FP)
+	MOVL AX, rr+20(FP)
+	RET
+
+// encryptBlocksCBC mirrors encryptBlocksCBCGeneric in cbc.go, with the
+// rounds of encryptBlock written out in the loop.
+//
+// func encryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (rl, rr uint32)
+TEXT ·encryptBlocksCBC(SB), NOSPLIT, $0-72
+	MOVQ c+0(FP), SI
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), R8
+	MOVQ src_len+40(FP), R9
+	MOVL l+56(FP), AX
+	MOVL r+60(FP), BX
+	SHRQ $3, R9
+	JZ encdone
+
+encloop:
+	MOVL 0(R8), CX
+	BSWAPL CX
+	XORL CX, AX
+	MOVL 4(R8), CX
+	BSWAPL CX
+	XORL CX, BX
+	XORL 0(SI), AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 4(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 8(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 12(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 16(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 20(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 24(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 28(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 32(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 36(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 40(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 44(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 48(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 52(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 56(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 60(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 64(SI), DX
+	XORL DX, AX
+	XORL 68(SI), BX
+	XCHGL AX, BX
+	MOVL AX, CX
+	BSWAPL CX
+	MOVL CX, 0(DI)
+	MOVL BX, CX
+	BSWAPL CX
+	MOVL CX, 4(DI)
+	ADDQ $8, R8
+	ADDQ $8, DI
+	DECQ R9
+	JNZ encloop
+
+encdone:
+	MOVL AX, rl+64(FP)
+	MOVL BX, rr+68(FP)
+	RET
+
+// decryptBlocksCBC mirrors decryptBlocksCBCGeneric in cbc.go, with the
+// rounds of decryptBlock written out in the loop.
+//
+// func decryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (rl, rr uint32)
+TEXT ·decryptBlocksCBC(SB), NOSPLIT, $0-72
+	MOVQ c+0(FP), SI
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), R8
+	MOVQ src_len+40(FP), R9
+	MOVL l+56(FP), R10
+	MOVL r+60(FP), R11
+	SHRQ $3, R9
+	JZ decdone
+
+decloop:
+	MOVL 0(R8), AX
+	BSWAPL AX
+	MOVL 4(R8), BX
+	BSWAPL BX
+	MOVL AX, R12
+	MOVL BX, R13
+	XORL 68(SI), AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 64(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 60(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 56(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 52(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 48(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 44(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 40(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 36(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 32(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 28(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 24(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 20(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 16(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 12(SI), DX
+	XORL DX, AX
+	MOVL AX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL AX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX AL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 8(SI), DX
+	XORL DX, BX
+	MOVL BX, CX
+	SHRL $24, CX
+	MOVL 72(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $16, CX
+	MOVBLZX CL, CX
+	ADDL 1096(SI)(CX*4), DX
+	MOVL BX, CX
+	SHRL $8, CX
+	MOVBLZX CL, CX
+	XORL 2120(SI)(CX*4), DX
+	MOVBLZX BL, CX
+	ADDL 3144(SI)(CX*4), DX
+	XORL 4(SI), DX
+	XORL DX, AX
+	XORL 0(SI), BX
+	XORL R10, BX
+	XORL R11, AX
+	BSWAPL BX
+	MOVL BX, 0(DI)
+	BSWAPL AX
+	MOVL AX, 4(DI)
+	MOVL R12, R10
+	MOVL R13, R11
+	ADDQ $8, R8
+	ADDQ $8, DI
+	DECQ R9
+	JNZ decloop
+
+decdone:
+	MOVL R10, rl+64(FP)
+	MOVL R11, rr+68(FP)
+	RET
@@ -0,0 +1,19 @@
+//go:build !amd64 || purego
+
+package blowfish
+
+func encryptBlock(l, r uint32, c *Cipher) (uint32, uint32) {
+	return encryptBlockGeneric(l, r, c)
+}
+
+func decryptBlock(l, r uint32, c *Cipher) (uint32, uint32) {
+	return decryptBlockGeneric(l, r, c)
+}
+
+func encryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (uint32, uint32) {
+	return encryptBlocksCBCGeneric(c, dst, src, l, r)
+}
+
+func decryptBlocksCBC(c *Cipher, dst, src []byte, l, r uint32) (uint32, uint32) {
+	return decryptBlocksCBCGeneric(c, dst, src, l, r)
+}
@@ -4,7 +4,13 @@
 
 package blowfish
 
-import "testing"
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
 
 type CryptTest struct {
 	key []byte
@@ -256,6 +262,259 @@ func TestSaltedCipher(t *testing.T) {
 	}
 }
 
+func TestWipe(t *testing.T) {
+	c, err := NewCipher([]byte("secret key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Wipe()
+	if *c != (Cipher{}) {
+		t.Error("key schedule survived Wipe")
+	}
+
+	if err := c.Reset(encryptTests[0].key); err != nil {
+		t.Fatal(err)
+	}
+	var buf [8]byte
+	c.Encrypt(buf[:], encryptTests[0].in)
+	if !bytes.Equal(buf[:], encryptTests[0].out) {
+		t.Errorf("after Wipe and Reset: %x, expected %x", buf, encryptTests[0].out)
+	}
+}
+
+func TestResetSalted(t *testing.T) {
+	var key, salt [32]byte
+	for i := range key {
+		key[i] = byte(i)
+		salt[i] = byte(i + 32)
+	}
+	var c Cipher
+	for i, v := range saltedVectors {
+		if err := c.ResetSalted(key[:], salt[:i]); err != nil {
+			t.Fatal(err)
+		}
+		var buf [8]byte
+		c.Encrypt(buf[:], buf[:])
+		if v != buf {
+			t.Errorf("%d: expected %x, got %x", i, v, buf)
+		}
+	}
+	if err := c.ResetSalted(nil, salt[:]); err != KeySizeError(0) {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
+func TestCloneAndMarshal(t *testing.T) {
+	test := encryptTests[0]
+	c, err := NewCipher(test.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := c.Clone()
+	b, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != ScheduleSize {
+		t.Fatalf("marshaled %d bytes, expected %d", len(b), ScheduleSize)
+	}
+	c.Wipe()
+
+	var restored Cipher
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Cipher{"clone": clone, "unmarshaled": &restored} {
+		var buf [8]byte
+		c.Encrypt(buf[:], test.in)
+		if !bytes.Equal(buf[:], test.out) {
+			t.Errorf("%s: %x, expected %x", name, buf, test.out)
+		}
+	}
+
+	// The initial key schedule is the hexadecimal digits of pi.
+	initCipher(&restored)
+	b, _ = restored.MarshalBinary()
+	if !bytes.HasPrefix(b, []byte{0x24, 0x3f, 0x6a, 0x88, 0x85, 0xa3, 0x08, 0xd3}) {
+		t.Errorf("initial schedule starts %x", b[:8])
+	}
+	if err := restored.UnmarshalBinary(b[1:]); err != ScheduleSizeError(ScheduleSize-1) {
+		t.Errorf("short schedule: %v", err)
+	}
+}
+
+func TestEncryptCBCInterleaved(t *testing.T) {
+	for n := 1; n <= 7; n++ {
+		streams := make([]CBCStream, n)
+		modes := make([]cipher.BlockMode, n)
+		expected := make([][]byte, n)
+		for i := range streams {
+			c, err := NewCipher([]byte{byte(n), byte(i), 1, 2, 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			iv := []byte{byte(i), 7, 6, 5, 4, 3, 2, 1}
+			streams[i].Cipher = c
+			copy(streams[i].IV[:], iv)
+			modes[i] = cipher.NewCBCEncrypter(c, iv)
+			expected[i] = make([]byte, 64)
+		}
+
+		// Two calls in a row must continue the chains.
+		for call := 0; call < 2; call++ {
+			for i := range streams {
+				src := make([]byte, 64)
+				for j := range src {
+					src[j] = byte(call*31 + i*17 + j)
+				}
+				modes[i].CryptBlocks(expected[i], src)
+				streams[i].Src = src
+				streams[i].Dst = src // in place
+			}
+			EncryptCBCInterleaved(streams)
+			for i := range streams {
+				if !bytes.Equal(streams[i].Dst, expected[i]) {
+					t.Errorf("%d streams, call %d, stream %d: %x, expected %x", n, call, i, streams[i].Dst, expected[i])
+				}
+			}
+		}
+	}
+}
+
+func benchmarkCBCInterleaved(b *testing.B, n int) {
+	streams := make([]CBCStream, n)
+	for i := range streams {
+		streams[i].Cipher, _ = NewCipher([]byte{byte(i)})
+		streams[i].Src = make([]byte, 2048)
+		streams[i].Dst = streams[i].Src
+	}
+	b.SetBytes(int64(n * 2048))
+	for i := 0; i < b.N; i++ {
+		EncryptCBCInterleaved(streams)
+	}
+}
+
+func BenchmarkCBCInterleaved1(b *testing.B) { benchmarkCBCInterleaved(b, 1) }
+func BenchmarkCBCInterleaved2(b *testing.B) { benchmarkCBCInterleaved(b, 2) }
+func BenchmarkCBCInterleaved4(b *testing.B) { benchmarkCBCInterleaved(b, 4) }
+func BenchmarkCBCInterleaved8(b *testing.B) { benchmarkCBCInterleaved(b, 8) }
+
+func TestCBC(t *testing.T) {
+	c, err := NewCipher([]byte("cbc test key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	src := make([]byte, 256)
+	for i := range src {
+		src[i] = byte(i * 7)
+	}
+
+	// Hiding the methods of Cipher forces crypto/cipher's own CBC.
+	generic := struct{ cipher.Block }{c}
+
+	enc := cipher.NewCBCEncrypter(c, iv)
+	if _, ok := enc.(*cbcEncrypter); !ok {
+		t.Fatalf("cipher.NewCBCEncrypter returned %T", enc)
+	}
+	expected := make([]byte, len(src))
+	cipher.NewCBCEncrypter(generic, iv).CryptBlocks(expected, src)
+
+	// Encrypt in two calls to check that the chain continues.
+	ciphertext := make([]byte, len(src))
+	enc.CryptBlocks(ciphertext[:64], src[:64])
+	enc.CryptBlocks(ciphertext[64:], src[64:])
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("encrypted %x, expected %x", ciphertext, expected)
+	}
+
+	enc.(interface{ SetIV([]byte) }).SetIV(iv)
+	buf := append([]byte(nil), src...)
+	enc.CryptBlocks(buf, buf)
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("after SetIV, in place: %x, expected %x", buf, expected)
+	}
+
+	dec := cipher.NewCBCDecrypter(c, iv)
+	if _, ok := dec.(*cbcDecrypter); !ok {
+		t.Fatalf("cipher.NewCBCDecrypter returned %T", dec)
+	}
+	dec.CryptBlocks(buf[:128], buf[:128])
+	dec.CryptBlocks(buf[128:], buf[128:])
+	if !bytes.Equal(buf, src) {
+		t.Errorf("decrypted %x, expected %x", buf, src)
+	}
+}
+
+func TestCryptBlocksXOR(t *testing.T) {
+	c, err := NewCipher([]byte("xor test key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := []byte{8, 7, 6, 5, 4, 3, 2, 1}
+	fill := func(seed byte) []byte {
+		b := make([]byte, 128)
+		for i := range b {
+			b[i] = seed + byte(i*13)
+		}
+		return b
+	}
+
+	for _, same := range []bool{false, true} {
+		dst, src, acc := fill(1), fill(2), fill(3)
+		if same {
+			src = dst
+		}
+
+		// The unfused steps, as battcrypt's main loop used to do them.
+		expected, expectedAcc := append([]byte(nil), dst...), append([]byte(nil), acc...)
+		for i := range expected {
+			expected[i] ^= src[i] ^ acc[i]
+		}
+		generic := cipher.NewCBCEncrypter(struct{ cipher.Block }{c}, iv)
+		generic.CryptBlocks(expected[:64], expected[:64])
+		generic.CryptBlocks(expected[64:], expected[64:])
+		for i := range expectedAcc {
+			expectedAcc[i] ^= expected[i]
+		}
+
+		x := cipher.NewCBCEncrypter(c, iv).(*cbcEncrypter)
+		x.CryptBlocksXOR(dst[:64], src[:64], acc[:64])
+		x.CryptBlocksXOR(dst[64:], src[64:], acc[64:])
+		if !bytes.Equal(dst, expected) || !bytes.Equal(acc, expectedAcc) {
+			t.Errorf("src == dst %v: %x %x, expected %x %x", same, dst, acc, expected, expectedAcc)
+		}
+	}
+}
+
+func benchmarkCBC(b *testing.B, mode cipher.BlockMode) {
+	buf := make([]byte, 2048)
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		mode.CryptBlocks(buf, buf)
+	}
+}
+
+func BenchmarkCBCEncrypt(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	benchmarkCBC(b, cipher.NewCBCEncrypter(c, make([]byte, BlockSize)))
+}
+
+func BenchmarkCBCDecrypt(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	benchmarkCBC(b, cipher.NewCBCDecrypter(c, make([]byte, BlockSize)))
+}
+
+func BenchmarkCBCEncryptGeneric(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	benchmarkCBC(b, cipher.NewCBCEncrypter(struct{ cipher.Block }{c}, make([]byte, BlockSize)))
+}
+
+func BenchmarkCBCDecryptGeneric(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	benchmarkCBC(b, cipher.NewCBCDecrypter(struct{ cipher.Block }{c}, make([]byte, BlockSize)))
+}
+
 func BenchmarkExpandKeyWithSalt(b *testing.B) {
 	key := make([]byte, 32)
 	salt := make([]byte, 16)
@@ -272,3 +531,105 @@ func BenchmarkExpandKey(b *testing.B) {
 		ExpandKey(key, c)
 	}
 }
+
+// The tests below compare the assembly implementation, where there is one,
+// with the Go code it replaces. Under the purego build tag they compare the
+// Go code with itself.
+
+func TestBlockImplementations(t *testing.T) {
+	for i, tt := range encryptTests {
+		c, err := NewCipher(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := binary.BigEndian.Uint32(tt.in[0:])
+		r := binary.BigEndian.Uint32(tt.in[4:])
+		el, er := binary.BigEndian.Uint32(tt.out[0:]), binary.BigEndian.Uint32(tt.out[4:])
+		if gl, gr := encryptBlockGeneric(l, r, c); gl != el || gr != er {
+			t.Errorf("%d: encryptBlockGeneric = %08x%08x, expected %08x%08x", i, gl, gr, el, er)
+		}
+		if gl, gr := encryptBlock(l, r, c); gl != el || gr != er {
+			t.Errorf("%d: encryptBlock = %08x%08x, expected %08x%08x", i, gl, gr, el, er)
+		}
+		if gl, gr := decryptBlockGeneric(el, er, c); gl != l || gr != r {
+			t.Errorf("%d: decryptBlockGeneric = %08x%08x, expected %08x%08x", i, gl, gr, l, r)
+		}
+		if gl, gr := decryptBlock(el, er, c); gl != l || gr != r {
+			t.Errorf("%d: decryptBlock = %08x%08x, expected %08x%08x", i, gl, gr, l, r)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		key := make([]byte, 1+rng.Intn(56))
+		rng.Read(key)
+		c, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 100; j++ {
+			l, r := rng.Uint32(), rng.Uint32()
+			al, ar := encryptBlock(l, r, c)
+			gl, gr := encryptBlockGeneric(l, r, c)
+			if al != gl || ar != gr {
+				t.Fatalf("key %x, block %08x%08x: encryptBlock = %08x%08x, generic %08x%08x", key, l, r, al, ar, gl, gr)
+			}
+			al, ar = decryptBlock(l, r, c)
+			gl, gr = decryptBlockGeneric(l, r, c)
+			if al != gl || ar != gr {
+				t.Fatalf("key %x, block %08x%08x: decryptBlock = %08x%08x, generic %08x%08x", key, l, r, al, ar, gl, gr)
+			}
+		}
+
+		src := make([]byte, 8*rng.Intn(40))
+		rng.Read(src)
+		l, r := rng.Uint32(), rng.Uint32()
+		dst, expected := make([]byte, len(src)), make([]byte, len(src))
+		al, ar := encryptBlocksCBC(c, dst, src, l, r)
+		gl, gr := encryptBlocksCBCGeneric(c, expected, src, l, r)
+		if !bytes.Equal(dst, expected) || al != gl || ar != gr {
+			t.Fatalf("key %x: encryptBlocksCBC differs from generic", key)
+		}
+		al, ar = decryptBlocksCBC(c, dst, expected, l, r)
+		gl, gr = decryptBlocksCBCGeneric(c, expected, expected, l, r)
+		if !bytes.Equal(dst, src) || !bytes.Equal(expected, src) || al != gl || ar != gr {
+			t.Fatalf("key %x: decryptBlocksCBC differs from generic", key)
+		}
+	}
+}
+
+func BenchmarkEncryptBlock(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	var l, r uint32
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		l, r = encryptBlock(l, r, c)
+	}
+}
+
+func BenchmarkEncryptBlockGeneric(b *testing.B) {
+	c, _ := NewCipher([]byte{0})
+	var l, r uint32
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		l, r = encryptBlockGeneric(l, r, c)
+	}
+}
+
+func benchmarkBlocksCBC(b *testing.B, crypt func(c *Cipher, dst, src []byte, l, r uint32) (uint32, uint32)) {
+	c, _ := NewCipher([]byte{0})
+	buf := make([]byte, 2048)
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		crypt(c, buf, buf, 0, 0)
+	}
+}
+
+func BenchmarkEncryptBlocksCBC(b *testing.B) { benchmarkBlocksCBC(b, encryptBlocksCBC) }
+func BenchmarkEncryptBlocksCBCGeneric(b *testing.B) {
+	benchmarkBlocksCBC(b, encryptBlocksCBCGeneric)
+}
+func BenchmarkDecryptBlocksCBC(b *testing.B) { benchmarkBlocksCBC(b, decryptBlocksCBC) }
+func BenchmarkDecryptBlocksCBCGeneric(b *testing.B) {
+	benchmarkBlocksCBC(b, decryptBlocksCBCGeneric)
+}
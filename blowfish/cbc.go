@@ -0,0 +1,189 @@
+package blowfish
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+
+	"github.com/BenLubar/battcrypt/blowfish/internal/alias"
+)
+
+// CBC mode working on the two halves of each block directly, rather than
+// through the byte slices of the cipher.Block interface. crypto/cipher's
+// NewCBCEncrypter and NewCBCDecrypter use these automatically.
+
+type cbc struct {
+	c    *Cipher
+	l, r uint32
+}
+
+type cbcEncrypter cbc
+
+type cbcDecrypter cbc
+
+// NewCBCEncrypter returns a cipher.BlockMode which encrypts in cipher block
+// chaining mode, using c and the initialization vector iv. It is called by
+// cipher.NewCBCEncrypter. The returned BlockMode also has a SetIV method.
+func (c *Cipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCEncrypter: IV length must equal block size")
+	}
+	x := &cbcEncrypter{c: c}
+	x.SetIV(iv)
+	return x
+}
+
+// NewCBCDecrypter returns a cipher.BlockMode which decrypts in cipher block
+// chaining mode, using c and the initialization vector iv. It is called by
+// cipher.NewCBCDecrypter. The returned BlockMode also has a SetIV method.
+func (c *Cipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCDecrypter: IV length must equal block size")
+	}
+	x := &cbcDecrypter{c: c}
+	x.SetIV(iv)
+	return x
+}
+
+func (x *cbcEncrypter) BlockSize() int { return BlockSize }
+
+func (x *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	checkBlocks(dst, src)
+	x.l, x.r = cbcEncrypt(x.c, dst, src, x.l, x.r)
+}
+
+// SetIV sets the chaining value, as if a block with the ciphertext iv had
+// just been encrypted.
+func (x *cbcEncrypter) SetIV(iv []byte) {
+	if len(iv) != BlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	x.l = binary.BigEndian.Uint32(iv[0:])
+	x.r = binary.BigEndian.Uint32(iv[4:])
+}
+
+func (x *cbcDecrypter) BlockSize() int { return BlockSize }
+
+func (x *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	checkBlocks(dst, src)
+	x.l, x.r = cbcDecrypt(x.c, dst, src, x.l, x.r)
+}
+
+// SetIV sets the chaining value, as if a block with the ciphertext iv had
+// just been decrypted.
+func (x *cbcDecrypter) SetIV(iv []byte) {
+	if len(iv) != BlockSize {
+		panic("cipher: incorrect length IV")
+	}
+	x.l = binary.BigEndian.Uint32(iv[0:])
+	x.r = binary.BigEndian.Uint32(iv[4:])
+}
+
+// cbcEncrypt and cbcDecrypt are encryptBlocksCBC and decryptBlocksCBC with
+// the implementation selected for c.
+
+func cbcEncrypt(c *Cipher, dst, src []byte, l, r uint32) (uint32, uint32) {
+	if !c.constantTime {
+		return encryptBlocksCBC(c, dst, src, l, r)
+	}
+	// Each block depends on the last, so they are done one at a time.
+	for i := 0; i+BlockSize <= len(src); i += BlockSize {
+		l ^= binary.BigEndian.Uint32(src[i:])
+		r ^= binary.BigEndian.Uint32(src[i+4:])
+		l, r = encryptBlockCT(l, r, c)
+		binary.BigEndian.PutUint32(dst[i:], l)
+		binary.BigEndian.PutUint32(dst[i+4:], r)
+	}
+	return l, r
+}
+
+func cbcDecrypt(c *Cipher, dst, src []byte, pl, pr uint32) (uint32, uint32) {
+	if !c.constantTime {
+		return decryptBlocksCBC(c, dst, src, pl, pr)
+	}
+	var bl, br [ctBlocks]uint32
+	for len(src) >= BlockSize {
+		n := len(src) / BlockSize
+		if n > ctBlocks {
+			n = ctBlocks
+		}
+		for k := 0; k < n; k++ {
+			bl[k] = binary.BigEndian.Uint32(src[k*BlockSize:])
+			br[k] = binary.BigEndian.Uint32(src[k*BlockSize+4:])
+		}
+		// The ciphertext is needed for chaining after dst, which may
+		// be src, has been written.
+		cl, cr := bl, br
+		decryptBlocksCT(c, &bl, &br, n)
+		for k := 0; k < n; k++ {
+			binary.BigEndian.PutUint32(dst[k*BlockSize:], bl[k]^pl)
+			binary.BigEndian.PutUint32(dst[k*BlockSize+4:], br[k]^pr)
+			pl, pr = cl[k], cr[k]
+		}
+		dst, src = dst[n*BlockSize:], src[n*BlockSize:]
+	}
+	return pl, pr
+}
+
+// encryptBlocksCBCGeneric is the Go version of encryptBlocksCBC.
+func encryptBlocksCBCGeneric(c *Cipher, dst, src []byte, l, r uint32) (uint32, uint32) {
+	for i := 0; i+BlockSize <= len(src); i += BlockSize {
+		l ^= binary.BigEndian.Uint32(src[i:])
+		r ^= binary.BigEndian.Uint32(src[i+4:])
+		l, r = encryptBlockGeneric(l, r, c)
+		binary.BigEndian.PutUint32(dst[i:], l)
+		binary.BigEndian.PutUint32(dst[i+4:], r)
+	}
+	return l, r
+}
+
+// decryptBlocksCBCGeneric is the Go version of decryptBlocksCBC.
+func decryptBlocksCBCGeneric(c *Cipher, dst, src []byte, pl, pr uint32) (uint32, uint32) {
+	for i := 0; i+BlockSize <= len(src); i += BlockSize {
+		// src is read before dst is written, so they may be the same.
+		cl := binary.BigEndian.Uint32(src[i:])
+		cr := binary.BigEndian.Uint32(src[i+4:])
+		l, r := decryptBlockGeneric(cl, cr, c)
+		binary.BigEndian.PutUint32(dst[i:], l^pl)
+		binary.BigEndian.PutUint32(dst[i+4:], r^pr)
+		pl, pr = cl, cr
+	}
+	return pl, pr
+}
+
+// checkBlocks panics, as crypto/cipher's CBC modes do, if src is not a whole
+// number of blocks or dst cannot hold it.
+func checkBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("crypto/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypto/cipher: output smaller than input")
+	}
+	if alias.InexactOverlap(dst[:len(src)], src) {
+		panic("crypto/cipher: invalid buffer overlap")
+	}
+}
+
+// CryptBlocksXOR encrypts dst XOR src XOR acc into dst, and then XORs the
+// ciphertext into acc, in a single pass. Each block is finished before the
+// next is read, so src may be dst, in which case it cancels out. All three
+// must have the same length, a multiple of BlockSize. It is the inner step
+// of battcrypt's main loop.
+func (x *cbcEncrypter) CryptBlocksXOR(dst, src, acc []byte) {
+	if len(dst)%BlockSize != 0 || len(src) != len(dst) || len(acc) != len(dst) {
+		panic("blowfish: CryptBlocksXOR arguments have different lengths")
+	}
+	c, l, r := x.c, x.l, x.r
+	for i := 0; i < len(dst); i += BlockSize {
+		al := binary.BigEndian.Uint32(acc[i:])
+		ar := binary.BigEndian.Uint32(acc[i+4:])
+		l ^= binary.BigEndian.Uint32(dst[i:]) ^ binary.BigEndian.Uint32(src[i:]) ^ al
+		r ^= binary.BigEndian.Uint32(dst[i+4:]) ^ binary.BigEndian.Uint32(src[i+4:]) ^ ar
+		l, r = c.encrypt(l, r)
+		binary.BigEndian.PutUint32(dst[i:], l)
+		binary.BigEndian.PutUint32(dst[i+4:], r)
+		binary.BigEndian.PutUint32(acc[i:], al^l)
+		binary.BigEndian.PutUint32(acc[i+4:], ar^r)
+	}
+	x.l, x.r = l, r
+}
@@ -1,7 +1,23 @@
 package blowfish
 
-// This package is identical to golang.org/x/crypto/blowfish, but with the
-// following method added and the import path removed.
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// This package is based on golang.org/x/crypto/blowfish, with the import
+// path removed. The x/crypto files are block.go, cipher.go and const.go,
+// which are changed only so that Cipher records which implementation it
+// uses, and encryption and decryption go through it. The additions are:
+//
+//   - the methods below;
+//   - a constant-time implementation, chosen with SetConstantTime or
+//     NewConstantTimeCipher, in ct.go;
+//   - CBC modes that work on the halves of each block directly, in cbc.go;
+//   - assembly for single blocks and CBC on amd64, in block_amd64.s, which
+//     the purego build tag turns off;
+//   - EncryptCBCInterleaved, which encrypts several CBC streams together,
+//     in interleave.go.
 
 func (c *Cipher) Reset(key []byte) error {
 	if k := len(key); k < 1 || k > 56 {
@@ -11,3 +27,69 @@ func (c *Cipher) Reset(key []byte) error {
 	ExpandKey(key, c)
 	return nil
 }
+
+// ResetSalted is like Reset, but folds salt into the key schedule as
+// NewSaltedCipher does. With an empty salt it is the same as Reset.
+func (c *Cipher) ResetSalted(key, salt []byte) error {
+	if len(salt) == 0 {
+		return c.Reset(key)
+	}
+	if k := len(key); k < 1 {
+		return KeySizeError(k)
+	}
+	initCipher(c)
+	expandKeyWithSalt(key, salt, c)
+	return nil
+}
+
+// Clone returns a copy of c, which can be used and changed independently.
+// Copying one Cipher into another with *dst = *src does the same without
+// allocating.
+func (c *Cipher) Clone() *Cipher {
+	clone := *c
+	return &clone
+}
+
+// ScheduleSize is the length of a key schedule encoded by MarshalBinary.
+const ScheduleSize = (18 + 4*256) * 4
+
+type ScheduleSizeError int
+
+func (k ScheduleSizeError) Error() string {
+	return "crypto/blowfish: invalid key schedule size " + strconv.Itoa(int(k))
+}
+
+// MarshalBinary encodes the key schedule: the P-array followed by the four
+// S-boxes, as big-endian 32-bit words. The choice made with SetConstantTime
+// is not included.
+func (c *Cipher) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, ScheduleSize)
+	for _, words := range [][]uint32{c.p[:], c.s0[:], c.s1[:], c.s2[:], c.s3[:]} {
+		for _, w := range words {
+			b = binary.BigEndian.AppendUint32(b, w)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary replaces the key schedule with one encoded by
+// MarshalBinary.
+func (c *Cipher) UnmarshalBinary(data []byte) error {
+	if len(data) != ScheduleSize {
+		return ScheduleSizeError(len(data))
+	}
+	for _, words := range [][]uint32{c.p[:], c.s0[:], c.s1[:], c.s2[:], c.s3[:]} {
+		for i := range words {
+			words[i] = binary.BigEndian.Uint32(data)
+			data = data[4:]
+		}
+	}
+	return nil
+}
+
+// Wipe overwrites the key schedule with zeros. The Cipher must be given a
+// new key with Reset before it is used again. The choice made with
+// SetConstantTime is kept.
+func (c *Cipher) Wipe() {
+	*c = Cipher{constantTime: c.constantTime}
+}
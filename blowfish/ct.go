@@ -0,0 +1,113 @@
+package blowfish
+
+// A constant-time implementation of Blowfish. Rather than indexing the
+// S-boxes with secret bytes, each round reads every entry of every S-box and
+// keeps the wanted ones with masks, so the memory accessed does not depend
+// on the key or the data. Up to ctBlocks blocks share each scan of the
+// S-boxes, which is where almost all of the time goes.
+
+// ctBlocks is the most blocks encrypted together by encryptBlocksCT.
+const ctBlocks = 8
+
+// NewConstantTimeCipher is like NewCipher, but returns a Cipher that uses
+// the constant-time implementation, including for its key schedule.
+func NewConstantTimeCipher(key []byte) (*Cipher, error) {
+	result := &Cipher{constantTime: true}
+	if err := result.Reset(key); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetConstantTime selects the implementation used by c. The constant-time
+// implementation does not read memory at addresses that depend on the key
+// or the data, so it does not leak them through cache timing, but it is
+// much slower. The key schedule is only protected if this is set before the
+// key is given to Reset.
+func (c *Cipher) SetConstantTime(on bool) {
+	c.constantTime = on
+}
+
+// ctMask returns all ones if x is 0, and 0 if x is between 1 and 255.
+func ctMask(x uint32) uint32 {
+	return uint32(int32(x-1) >> 31)
+}
+
+// ctRound sets xb[k] ^= F(xa[k]) ^ p for the first n blocks.
+func ctRound(c *Cipher, xa, xb *[ctBlocks]uint32, n int, p uint32) {
+	var f0, f1, f2, f3 [ctBlocks]uint32
+	for i := uint32(0); i < 256; i++ {
+		v0, v1, v2, v3 := c.s0[i], c.s1[i], c.s2[i], c.s3[i]
+		for k := 0; k < n; k++ {
+			x := xa[k]
+			f0[k] |= v0 & ctMask(x>>24^i)
+			f1[k] |= v1 & ctMask(x>>16&0xff^i)
+			f2[k] |= v2 & ctMask(x>>8&0xff^i)
+			f3[k] |= v3 & ctMask(x&0xff^i)
+		}
+	}
+	for k := 0; k < n; k++ {
+		xb[k] ^= ((f0[k] + f1[k]) ^ f2[k]) + f3[k] ^ p
+	}
+}
+
+// encryptBlocksCT encrypts the first n blocks of l and r in place.
+func encryptBlocksCT(c *Cipher, l, r *[ctBlocks]uint32, n int) {
+	for k := 0; k < n; k++ {
+		l[k] ^= c.p[0]
+	}
+	for i := 1; i <= 16; i += 2 {
+		ctRound(c, l, r, n, c.p[i])
+		ctRound(c, r, l, n, c.p[i+1])
+	}
+	for k := 0; k < n; k++ {
+		r[k] ^= c.p[17]
+		l[k], r[k] = r[k], l[k]
+	}
+}
+
+// decryptBlocksCT decrypts the first n blocks of l and r in place.
+func decryptBlocksCT(c *Cipher, l, r *[ctBlocks]uint32, n int) {
+	for k := 0; k < n; k++ {
+		l[k] ^= c.p[17]
+	}
+	for i := 16; i >= 1; i -= 2 {
+		ctRound(c, l, r, n, c.p[i])
+		ctRound(c, r, l, n, c.p[i-1])
+	}
+	for k := 0; k < n; k++ {
+		r[k] ^= c.p[0]
+		l[k], r[k] = r[k], l[k]
+	}
+}
+
+func encryptBlockCT(l, r uint32, c *Cipher) (uint32, uint32) {
+	var bl, br [ctBlocks]uint32
+	bl[0], br[0] = l, r
+	encryptBlocksCT(c, &bl, &br, 1)
+	return bl[0], br[0]
+}
+
+func decryptBlockCT(l, r uint32, c *Cipher) (uint32, uint32) {
+	var bl, br [ctBlocks]uint32
+	bl[0], br[0] = l, r
+	decryptBlocksCT(c, &bl, &br, 1)
+	return bl[0], br[0]
+}
+
+// encrypt and decrypt process one block with the implementation selected
+// for c.
+
+func (c *Cipher) encrypt(l, r uint32) (uint32, uint32) {
+	if c.constantTime {
+		return encryptBlockCT(l, r, c)
+	}
+	return encryptBlock(l, r, c)
+}
+
+func (c *Cipher) decrypt(l, r uint32) (uint32, uint32) {
+	if c.constantTime {
+		return decryptBlockCT(l, r, c)
+	}
+	return decryptBlock(l, r, c)
+}
@@ -0,0 +1,172 @@
+package blowfish
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"flag"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+var dudect = flag.Bool("dudect", false, "run the timing leakage test of the constant-time implementation")
+
+func TestConstantTimeCipher(t *testing.T) {
+	for i, tt := range encryptTests {
+		c, err := NewConstantTimeCipher(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf [BlockSize]byte
+		c.Encrypt(buf[:], tt.in)
+		if !bytes.Equal(buf[:], tt.out) {
+			t.Errorf("%d: encrypted %x, expected %x", i, buf, tt.out)
+		}
+		c.Decrypt(buf[:], tt.out)
+		if !bytes.Equal(buf[:], tt.in) {
+			t.Errorf("%d: decrypted %x, expected %x", i, buf, tt.in)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	key := make([]byte, 56)
+	rng.Read(key)
+	c, _ := NewCipher(key)
+	ct, _ := NewConstantTimeCipher(key)
+	if ct.p != c.p || ct.s0 != c.s0 || ct.s3 != c.s3 {
+		t.Fatal("constant-time key schedule differs")
+	}
+
+	// Every batch size, against the table implementation.
+	for n := 1; n <= ctBlocks; n++ {
+		var l, r, el, er [ctBlocks]uint32
+		for k := 0; k < n; k++ {
+			l[k], r[k] = rng.Uint32(), rng.Uint32()
+			el[k], er[k] = encryptBlockGeneric(l[k], r[k], c)
+		}
+		encryptBlocksCT(ct, &l, &r, n)
+		if l != el || r != er {
+			t.Errorf("%d blocks: encrypted %x %x, expected %x %x", n, l, r, el, er)
+		}
+	}
+
+	iv := make([]byte, BlockSize)
+	src := make([]byte, 8*(2*ctBlocks+3))
+	rng.Read(src)
+	expected := make([]byte, len(src))
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(expected, src)
+	buf := make([]byte, len(src))
+	cipher.NewCBCEncrypter(ct, iv).CryptBlocks(buf, src)
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("CBC encrypted %x, expected %x", buf, expected)
+	}
+	cipher.NewCBCDecrypter(ct, iv).CryptBlocks(buf, buf)
+	if !bytes.Equal(buf, src) {
+		t.Errorf("CBC decrypted %x, expected %x", buf, src)
+	}
+
+	streams := []CBCStream{{Cipher: ct, Dst: make([]byte, len(src)), Src: src}, {Cipher: c, Dst: make([]byte, len(src)), Src: src}}
+	EncryptCBCInterleaved(streams)
+	for k, s := range streams {
+		if !bytes.Equal(s.Dst, expected) {
+			t.Errorf("interleaved stream %d encrypted %x, expected %x", k, s.Dst, expected)
+		}
+	}
+
+	ct.Wipe()
+	if !ct.constantTime {
+		t.Error("Wipe forgot SetConstantTime")
+	}
+}
+
+// TestDudect looks for a difference in timing between encrypting a fixed
+// block and random blocks, in the style of dudect: the two classes of input
+// are measured in random order, and Welch's t-test is applied to the
+// measurements below several percentiles. A |t| above 4.5 suggests a leak.
+// It is slow and sensitive to the machine, so it only runs with -dudect.
+func TestDudect(t *testing.T) {
+	if !*dudect {
+		t.Skip("run with -dudect")
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	key := make([]byte, 16)
+	rng.Read(key)
+	ct, _ := NewConstantTimeCipher(key)
+	table, _ := NewCipher(key)
+
+	ctT := dudectT(rng, func(l, r uint32) { encryptBlockCT(l, r, ct) }, 20000)
+	tableT := dudectT(rng, func(l, r uint32) { encryptBlock(l, r, table) }, 2000000)
+	t.Logf("max |t|: constant-time %.2f, table %.2f", ctT, tableT)
+	if ctT > 4.5 {
+		t.Errorf("constant-time implementation may leak: |t| = %.2f", ctT)
+	}
+}
+
+// dudectT measures f n times and returns the largest |t| found.
+func dudectT(rng *rand.Rand, f func(l, r uint32), n int) float64 {
+	const fixedL, fixedR = 0, 0
+	class := make([]bool, n)
+	times := make([]float64, n)
+	for i := range class {
+		class[i] = rng.Intn(2) == 0
+		l, r := uint32(fixedL), uint32(fixedR)
+		if class[i] {
+			l, r = rng.Uint32(), rng.Uint32()
+		}
+		start := time.Now()
+		f(l, r)
+		times[i] = float64(time.Since(start))
+	}
+
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	var maxT float64
+	for _, pct := range []float64{1, 0.99, 0.9, 0.75, 0.5} {
+		limit := sorted[int(pct*float64(n-1))]
+		var cnt [2]float64
+		var mean, m2 [2]float64
+		for i, x := range times {
+			if x > limit {
+				continue
+			}
+			k := 0
+			if class[i] {
+				k = 1
+			}
+			// Welford's online mean and variance
+			cnt[k]++
+			d := x - mean[k]
+			mean[k] += d / cnt[k]
+			m2[k] += d * (x - mean[k])
+		}
+		if cnt[0] < 2 || cnt[1] < 2 {
+			continue
+		}
+		v0, v1 := m2[0]/(cnt[0]-1), m2[1]/(cnt[1]-1)
+		if tt := math.Abs(mean[0]-mean[1]) / math.Sqrt(v0/cnt[0]+v1/cnt[1]); tt > maxT {
+			maxT = tt
+		}
+	}
+	return maxT
+}
+
+func BenchmarkEncryptBlockCT(b *testing.B) {
+	c, _ := NewConstantTimeCipher([]byte{0})
+	var l, r uint32
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		l, r = encryptBlockCT(l, r, c)
+	}
+}
+
+func BenchmarkEncryptBlocksCT8(b *testing.B) {
+	c, _ := NewConstantTimeCipher([]byte{0})
+	var l, r [ctBlocks]uint32
+	b.SetBytes(BlockSize * ctBlocks)
+	for i := 0; i < b.N; i++ {
+		encryptBlocksCT(c, &l, &r, ctBlocks)
+	}
+}
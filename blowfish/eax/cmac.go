@@ -0,0 +1,122 @@
+package eax
+
+import (
+	"crypto/cipher"
+	"errors"
+	"hash"
+)
+
+// ErrBlockSize is returned for block ciphers whose blocks are not 64 or 128
+// bits long, as there is no CMAC polynomial for them here.
+var ErrBlockSize = errors.New("eax: block size must be 8 or 16 bytes")
+
+// The low bits of the polynomials used to double a subkey in GF(2^64) and
+// GF(2^128).
+const (
+	r64  = 0x1b
+	r128 = 0x87
+)
+
+type cmac struct {
+	b      cipher.Block
+	k1, k2 []byte
+
+	// x is the chaining value. buf holds up to one block of input, which
+	// is only encrypted once more input arrives, as the last block is
+	// treated differently.
+	x   []byte
+	buf []byte
+}
+
+// NewCMAC returns a hash.Hash computing OMAC1, better known as CMAC (NIST
+// SP 800-38B and RFC 4493), with b. The block size of b must be 8 or 16
+// bytes, and the MAC is the same length.
+func NewCMAC(b cipher.Block) (hash.Hash, error) {
+	k1, k2, err := subkeys(b)
+	if err != nil {
+		return nil, err
+	}
+	return newCMAC(b, k1, k2), nil
+}
+
+func newCMAC(b cipher.Block, k1, k2 []byte) *cmac {
+	bs := b.BlockSize()
+	return &cmac{b: b, k1: k1, k2: k2, x: make([]byte, bs), buf: make([]byte, 0, bs)}
+}
+
+// subkeys derives the two CMAC subkeys by doubling the encryption of the
+// zero block.
+func subkeys(b cipher.Block) (k1, k2 []byte, err error) {
+	var r byte
+	switch b.BlockSize() {
+	case 8:
+		r = r64
+	case 16:
+		r = r128
+	default:
+		return nil, nil, ErrBlockSize
+	}
+	k1 = make([]byte, b.BlockSize())
+	k2 = make([]byte, b.BlockSize())
+	b.Encrypt(k1, k1)
+	double(k1, k1, r)
+	double(k2, k1, r)
+	return k1, k2, nil
+}
+
+// double sets dst to src multiplied by x in the field reduced by r.
+func double(dst, src []byte, r byte) {
+	carry := src[0] >> 7
+	for i := 0; i < len(src)-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[len(src)-1] = src[len(src)-1]<<1 ^ r&-carry
+}
+
+func (c *cmac) Size() int      { return c.b.BlockSize() }
+func (c *cmac) BlockSize() int { return c.b.BlockSize() }
+
+func (c *cmac) Reset() {
+	for i := range c.x {
+		c.x[i] = 0
+	}
+	c.buf = c.buf[:0]
+}
+
+func (c *cmac) Write(p []byte) (int, error) {
+	n := len(p)
+	bs := len(c.x)
+	for len(p) > 0 {
+		if len(c.buf) == bs {
+			xorBytes(c.x, c.buf)
+			c.b.Encrypt(c.x, c.x)
+			c.buf = c.buf[:0]
+		}
+		k := copy(c.buf[len(c.buf):bs], p)
+		c.buf = c.buf[:len(c.buf)+k]
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (c *cmac) Sum(in []byte) []byte {
+	bs := len(c.x)
+	x := make([]byte, bs)
+	copy(x, c.x)
+	xorBytes(x, c.buf)
+	if len(c.buf) == bs {
+		xorBytes(x, c.k1)
+	} else {
+		x[len(c.buf)] ^= 0x80
+		xorBytes(x, c.k2)
+	}
+	c.b.Encrypt(x, x)
+	return append(in, x...)
+}
+
+// xorBytes sets dst[i] ^= src[i] for every byte of src.
+func xorBytes(dst, src []byte) {
+	for i, v := range src {
+		dst[i] ^= v
+	}
+}
@@ -0,0 +1,169 @@
+// Package eax implements the EAX mode of authenticated encryption (Bellare,
+// Rogaway and Wagner), and the OMAC1 message authentication code it is built
+// on, for block ciphers with 64-bit blocks such as Blowfish, as well as for
+// ciphers with 128-bit blocks.
+//
+// With 64-bit blocks, collisions between cipher blocks become likely after
+// about 2^32 blocks under one key, which is what the Sweet32 attacks exploit.
+// Each AEAD therefore counts the blocks of the messages it seals and opens,
+// and stops working long before then. Messages that fail authentication are
+// not counted, so forged ciphertexts cannot use up the limit. The count is per
+// AEAD, so it only protects the key if all messages under the key go through
+// the same AEAD.
+package eax
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"sync/atomic"
+
+	"github.com/BenLubar/battcrypt/blowfish"
+	"github.com/BenLubar/battcrypt/blowfish/internal/alias"
+)
+
+// Limits on the number of blocks, counting every call to the block cipher
+// made for a message, processed by one AEAD. Going up to Limit64 blocks
+// gives a chance of about 2^-19 that any two blocks collide.
+const (
+	Limit64  = 1 << 23
+	Limit128 = 1 << 48
+)
+
+// ErrLimit is returned by Open, and is the value Seal panics with, once the
+// AEAD has reached its limit.
+var ErrLimit = errors.New("eax: too much data processed with one key")
+
+var errOpen = errors.New("eax: message authentication failed")
+
+type eax struct {
+	b      cipher.Block
+	k1, k2 []byte
+	limit  uint64
+
+	// used is the number of blocks sealed or opened so far, accessed
+	// atomically.
+	used uint64
+}
+
+// New returns an EAX AEAD using Blowfish with the given key, from 1 to 56
+// bytes.
+func New(key []byte) (cipher.AEAD, error) {
+	b, err := blowfish.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewEAX(b)
+}
+
+// NewEAX returns an EAX AEAD using b, which must have a block size of 8 or 16
+// bytes. Nonces and tags are one block long.
+//
+// Seal panics with ErrLimit, and Open returns ErrLimit, if a message would
+// take the total number of blocks processed past Limit64 or Limit128, and
+// every call after that fails the same way. Open only counts a message once
+// it has been authenticated.
+func NewEAX(b cipher.Block) (cipher.AEAD, error) {
+	k1, k2, err := subkeys(b)
+	if err != nil {
+		return nil, err
+	}
+	var limit uint64 = Limit128
+	if b.BlockSize() == 8 {
+		limit = Limit64
+	}
+	return &eax{b: b, k1: k1, k2: k2, limit: limit}, nil
+}
+
+func (e *eax) NonceSize() int { return e.b.BlockSize() }
+func (e *eax) Overhead() int  { return e.b.BlockSize() }
+
+// reserve counts the blocks used for a message with the given nonce,
+// additional data and plaintext lengths, and reports whether the limit
+// allows it.
+func (e *eax) reserve(nonce, ad, text int) bool {
+	bs := e.b.BlockSize()
+	blocks := func(n int) uint64 { return uint64((n + bs - 1) / bs) }
+	// One block for each of the three OMACs' tweaks, the inputs to the
+	// OMACs, and the counter mode keystream.
+	n := 3 + blocks(nonce) + blocks(ad) + 2*blocks(text)
+	return atomic.AddUint64(&e.used, n) <= e.limit
+}
+
+// omac returns the OMAC of a block holding t followed by data.
+func (e *eax) omac(t byte, data []byte) []byte {
+	c := newCMAC(e.b, e.k1, e.k2)
+	tweak := make([]byte, e.b.BlockSize())
+	tweak[len(tweak)-1] = t
+	c.Write(tweak)
+	c.Write(data)
+	return c.Sum(nil)
+}
+
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != e.NonceSize() {
+		panic("eax: incorrect nonce length given to EAX")
+	}
+	if !e.reserve(len(nonce), len(additionalData), len(plaintext)) {
+		panic(ErrLimit)
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+e.Overhead())
+	if alias.InexactOverlap(out, plaintext) {
+		panic("eax: invalid buffer overlap")
+	}
+
+	n := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+	cipher.NewCTR(e.b, n).XORKeyStream(out, plaintext)
+	tag := e.omac(2, out[:len(plaintext)])
+	xorBytes(tag, n)
+	xorBytes(tag, h)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != e.NonceSize() {
+		panic("eax: incorrect nonce length given to EAX")
+	}
+	if len(ciphertext) < e.Overhead() {
+		return nil, errOpen
+	}
+	text, sent := ciphertext[:len(ciphertext)-e.Overhead()], ciphertext[len(ciphertext)-e.Overhead():]
+
+	ret, out := sliceForAppend(dst, len(text))
+	if alias.InexactOverlap(out, text) {
+		panic("eax: invalid buffer overlap")
+	}
+
+	n := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+	tag := e.omac(2, text)
+	xorBytes(tag, n)
+	xorBytes(tag, h)
+	if subtle.ConstantTimeCompare(tag, sent) != 1 {
+		return nil, errOpen
+	}
+
+	// Only now that the message is known to be genuine is it counted.
+	if !e.reserve(len(nonce), len(additionalData), len(text)) {
+		return nil, ErrLimit
+	}
+	cipher.NewCTR(e.b, n).XORKeyStream(out, text)
+	return ret, nil
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,183 @@
+package eax
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+
+	"github.com/BenLubar/battcrypt/blowfish"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newBlock(name string, key []byte) cipher.Block {
+	var b cipher.Block
+	var err error
+	switch name {
+	case "blowfish":
+		b, err = blowfish.NewCipher(key)
+	case "tdea":
+		b, err = des.NewTripleDESCipher(key)
+	case "aes":
+		b, err = aes.NewCipher(key)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// The AES vectors are from RFC 4493. The rest were computed with OpenSSL.
+var cmacTests = []struct {
+	cipher, key, msg, mac string
+}{
+	{"aes", "2b7e151628aed2a6abf7158809cf4f3c", "", "bb1d6929e95937287fa37d129b756746"},
+	{"aes", "2b7e151628aed2a6abf7158809cf4f3c", "6bc1bee22e409f96e93d7e117393172a", "070a16b46b4d4144f79bdd9dd04a287c"},
+	{"tdea", "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5", "", "b7a688e122ffaf95"},
+	{"tdea", "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5", "6bc1bee22e409f96e93d7e117393172a", "286d394673448197"},
+	{"tdea", "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5", "6bc1bee22e409f96e93d7e117393172aae2d8a57", "743ddbe0ce2dc2ed"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "", "eb465812a5e94ad8"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "6bc1bee22e409f96", "56c836034ab3f5cf"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "6bc1bee22e409f96e93d7e117393172aae2d8a57", "a01559b349d7ce79"},
+}
+
+func TestCMAC(t *testing.T) {
+	for i, test := range cmacTests {
+		h, err := NewCMAC(newBlock(test.cipher, decodeHex(test.key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := decodeHex(test.msg)
+		// Write in uneven pieces to check the buffering.
+		for len(msg) > 3 {
+			h.Write(msg[:3])
+			msg = msg[3:]
+		}
+		h.Write(msg)
+		if mac := hex.EncodeToString(h.Sum(nil)); mac != test.mac {
+			t.Errorf("%d: got %s, expected %s", i, mac, test.mac)
+		}
+		h.Reset()
+		h.Write(decodeHex(test.msg))
+		if mac := hex.EncodeToString(h.Sum(nil)); mac != test.mac {
+			t.Errorf("%d: after Reset: got %s, expected %s", i, mac, test.mac)
+		}
+	}
+
+	if _, err := NewCMAC(&oddBlock{}); err != ErrBlockSize {
+		t.Errorf("unexpected error %v for 4-byte blocks", err)
+	}
+}
+
+type oddBlock struct{ cipher.Block }
+
+func (*oddBlock) BlockSize() int { return 4 }
+
+// The AES vectors are from the EAX paper. The Blowfish vectors were computed
+// from OpenSSL's Blowfish CMAC and ECB.
+var eaxTests = []struct {
+	cipher, key, nonce, header, msg, out string
+}{
+	{"aes", "233952dee4d5ed5f9b9c6d6ff80ff478", "62ec67f9c3a4a407fcb2a8c49031a8b3", "6bfb914fd07eae6b", "", "e037830e8389f27b025a2d6527e79d01"},
+	{"aes", "91945d3f4dcbee0bf45ef52255f095a4", "becaf043b0a23d843194ba972c66debd", "fa3bfd4806eb53fa", "f7fb", "19dd5c4c9331049d0bdab0277408f67967e5"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "0001020304050607", "", "", "f8efee26ccc18412"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "f0f1f2f3f4f5f6f7", "686561646572", "426c6f7766697368", "d30168b2e0e95234b818c5ae489de19e"},
+	{"blowfish", "000102030405060708090a0b0c0d0e0f", "ffffffffffffffff", "0102030405060708090a", "61206d657373616765206f66207365766572616c20626c6f636b7321", "0634a63d212a15dea3382e12d0d9ee268c722b8eb714e3a56229cb836e4e28ad69f8d79e"},
+}
+
+func TestEAX(t *testing.T) {
+	for i, test := range eaxTests {
+		aead, err := NewEAX(newBlock(test.cipher, decodeHex(test.key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, header, msg := decodeHex(test.nonce), decodeHex(test.header), decodeHex(test.msg)
+		out := aead.Seal([]byte("prefix"), nonce, msg, header)
+		if got := hex.EncodeToString(out[6:]); string(out[:6]) != "prefix" || got != test.out {
+			t.Errorf("%d: sealed %q %s, expected %s", i, out[:6], got, test.out)
+		}
+
+		plain, err := aead.Open(nil, nonce, out[6:], header)
+		if err != nil || !bytes.Equal(plain, msg) {
+			t.Errorf("%d: opened %x, %v; expected %x", i, plain, err, msg)
+		}
+		for j := range out[6:] {
+			out[6+j] ^= 1
+			if _, err := aead.Open(nil, nonce, out[6:], header); err != errOpen {
+				t.Errorf("%d: changing byte %d gave %v", i, j, err)
+			}
+			out[6+j] ^= 1
+		}
+		if _, err := aead.Open(nil, nonce, out[6:], append(header, 0)); err != errOpen {
+			t.Errorf("%d: changing the header gave %v", i, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	aead, err := New([]byte("secret key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aead.NonceSize() != blowfish.BlockSize || aead.Overhead() != blowfish.BlockSize {
+		t.Errorf("nonce size %d and overhead %d", aead.NonceSize(), aead.Overhead())
+	}
+	if _, err := New(nil); err != blowfish.KeySizeError(0) {
+		t.Errorf("empty key: %v", err)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	aead, _ := New([]byte("secret key"))
+	if aead.(*eax).limit != Limit64 {
+		t.Fatalf("limit is %d", aead.(*eax).limit)
+	}
+	aead.(*eax).limit = 2 * (3 + 1 + 2*2)
+	nonce := make([]byte, aead.NonceSize())
+	msg := make([]byte, 16)
+	sealed := aead.Seal(nil, nonce, msg, nil)
+
+	// Forgeries must not use up the limit.
+	forged := append([]byte(nil), sealed...)
+	forged[0] ^= 1
+	for i := 0; i < 10; i++ {
+		if _, err := aead.Open(nil, nonce, forged, nil); err != errOpen {
+			t.Fatalf("forged message: %v", err)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, sealed, nil); err != nil {
+		t.Fatalf("Open within the limit: %v", err)
+	}
+
+	if _, err := aead.Open(nil, nonce, sealed, nil); err != ErrLimit {
+		t.Errorf("Open past the limit: %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r != ErrLimit {
+			t.Errorf("Seal past the limit: panicked with %v", r)
+		}
+	}()
+	aead.Seal(nil, nonce, nil, nil)
+}
+
+func BenchmarkSeal(b *testing.B) {
+	aead, _ := New([]byte("secret key"))
+	nonce := make([]byte, aead.NonceSize())
+	msg := make([]byte, 1024)
+	out := make([]byte, 0, len(msg)+aead.Overhead())
+	b.SetBytes(int64(len(msg)))
+	for i := 0; i < b.N; i++ {
+		aead.(*eax).used = 0
+		aead.Seal(out, nonce, msg, nil)
+	}
+}
@@ -0,0 +1,238 @@
+package blowfish
+
+import "encoding/binary"
+
+// A CBCStream is one CBC encryption advanced by EncryptCBCInterleaved.
+type CBCStream struct {
+	Cipher *Cipher
+	// IV is the previous ciphertext block. It is updated to the last
+	// block encrypted, so that the stream can be continued by another
+	// call.
+	IV       [BlockSize]byte
+	Dst, Src []byte
+}
+
+// EncryptCBCInterleaved encrypts the Src of each stream into its Dst in CBC
+// mode. The result is the same as encrypting each stream on its own with
+// crypto/cipher's CBC encrypter, but the streams are processed together with
+// their rounds interleaved, so that the S-box lookups of one stream can
+// proceed while another is waiting on its own. Every Src must have the same
+// length, which must be a multiple of BlockSize, and Dst must be at least as
+// long. Dst may equal Src. If any of the Ciphers uses the constant-time
+// implementation, the streams are encrypted one at a time instead.
+func EncryptCBCInterleaved(streams []CBCStream) {
+	for _, s := range streams {
+		if s.Cipher.constantTime {
+			for i := range streams {
+				encryptCBC1(&streams[i])
+			}
+			return
+		}
+	}
+	for len(streams) >= 4 {
+		encryptCBC4(streams[:4])
+		streams = streams[4:]
+	}
+	if len(streams) >= 2 {
+		encryptCBC2(streams[:2])
+		streams = streams[2:]
+	}
+	if len(streams) == 1 {
+		encryptCBC1(&streams[0])
+	}
+}
+
+func encryptCBC1(s *CBCStream) {
+	l := binary.BigEndian.Uint32(s.IV[0:])
+	r := binary.BigEndian.Uint32(s.IV[4:])
+	l, r = cbcEncrypt(s.Cipher, s.Dst, s.Src, l, r)
+	binary.BigEndian.PutUint32(s.IV[0:], l)
+	binary.BigEndian.PutUint32(s.IV[4:], r)
+}
+
+func encryptCBC2(s []CBCStream) {
+	s0, s1 := &s[0], &s[1]
+	c0, dst0, src0 := s0.Cipher, s0.Dst, s0.Src
+	c1, dst1, src1 := s1.Cipher, s1.Dst, s1.Src
+	if len(src1) != len(src0) {
+		panic("blowfish: interleaved streams have different lengths")
+	}
+	l0, r0 := binary.BigEndian.Uint32(s0.IV[0:]), binary.BigEndian.Uint32(s0.IV[4:])
+	l1, r1 := binary.BigEndian.Uint32(s1.IV[0:]), binary.BigEndian.Uint32(s1.IV[4:])
+	for i := 0; i+BlockSize <= len(src0); i += BlockSize {
+		l0 ^= binary.BigEndian.Uint32(src0[i:])
+		r0 ^= binary.BigEndian.Uint32(src0[i+4:])
+		l1 ^= binary.BigEndian.Uint32(src1[i:])
+		r1 ^= binary.BigEndian.Uint32(src1[i+4:])
+		l0, r0, l1, r1 = encryptBlock2(l0, r0, c0, l1, r1, c1)
+		binary.BigEndian.PutUint32(dst0[i:], l0)
+		binary.BigEndian.PutUint32(dst0[i+4:], r0)
+		binary.BigEndian.PutUint32(dst1[i:], l1)
+		binary.BigEndian.PutUint32(dst1[i+4:], r1)
+	}
+	binary.BigEndian.PutUint32(s0.IV[0:], l0)
+	binary.BigEndian.PutUint32(s0.IV[4:], r0)
+	binary.BigEndian.PutUint32(s1.IV[0:], l1)
+	binary.BigEndian.PutUint32(s1.IV[4:], r1)
+}
+
+func encryptCBC4(s []CBCStream) {
+	s0, s1, s2, s3 := &s[0], &s[1], &s[2], &s[3]
+	c0, dst0, src0 := s0.Cipher, s0.Dst, s0.Src
+	c1, dst1, src1 := s1.Cipher, s1.Dst, s1.Src
+	c2, dst2, src2 := s2.Cipher, s2.Dst, s2.Src
+	c3, dst3, src3 := s3.Cipher, s3.Dst, s3.Src
+	if len(src1) != len(src0) || len(src2) != len(src0) || len(src3) != len(src0) {
+		panic("blowfish: interleaved streams have different lengths")
+	}
+	l0, r0 := binary.BigEndian.Uint32(s0.IV[0:]), binary.BigEndian.Uint32(s0.IV[4:])
+	l1, r1 := binary.BigEndian.Uint32(s1.IV[0:]), binary.BigEndian.Uint32(s1.IV[4:])
+	l2, r2 := binary.BigEndian.Uint32(s2.IV[0:]), binary.BigEndian.Uint32(s2.IV[4:])
+	l3, r3 := binary.BigEndian.Uint32(s3.IV[0:]), binary.BigEndian.Uint32(s3.IV[4:])
+	for i := 0; i+BlockSize <= len(src0); i += BlockSize {
+		l0 ^= binary.BigEndian.Uint32(src0[i:])
+		r0 ^= binary.BigEndian.Uint32(src0[i+4:])
+		l1 ^= binary.BigEndian.Uint32(src1[i:])
+		r1 ^= binary.BigEndian.Uint32(src1[i+4:])
+		l2 ^= binary.BigEndian.Uint32(src2[i:])
+		r2 ^= binary.BigEndian.Uint32(src2[i+4:])
+		l3 ^= binary.BigEndian.Uint32(src3[i:])
+		r3 ^= binary.BigEndian.Uint32(src3[i+4:])
+		l0, r0, l1, r1, l2, r2, l3, r3 = encryptBlock4(l0, r0, c0, l1, r1, c1, l2, r2, c2, l3, r3, c3)
+		binary.BigEndian.PutUint32(dst0[i:], l0)
+		binary.BigEndian.PutUint32(dst0[i+4:], r0)
+		binary.BigEndian.PutUint32(dst1[i:], l1)
+		binary.BigEndian.PutUint32(dst1[i+4:], r1)
+		binary.BigEndian.PutUint32(dst2[i:], l2)
+		binary.BigEndian.PutUint32(dst2[i+4:], r2)
+		binary.BigEndian.PutUint32(dst3[i:], l3)
+		binary.BigEndian.PutUint32(dst3[i+4:], r3)
+	}
+	binary.BigEndian.PutUint32(s0.IV[0:], l0)
+	binary.BigEndian.PutUint32(s0.IV[4:], r0)
+	binary.BigEndian.PutUint32(s1.IV[0:], l1)
+	binary.BigEndian.PutUint32(s1.IV[4:], r1)
+	binary.BigEndian.PutUint32(s2.IV[0:], l2)
+	binary.BigEndian.PutUint32(s2.IV[4:], r2)
+	binary.BigEndian.PutUint32(s3.IV[0:], l3)
+	binary.BigEndian.PutUint32(s3.IV[4:], r3)
+}
+
+// encryptBlock2 and encryptBlock4 are encryptBlock for two and four
+// independent blocks, with the rounds of each block interleaved.
+
+func encryptBlock2(l0, r0 uint32, c0 *Cipher, l1, r1 uint32, c1 *Cipher) (uint32, uint32, uint32, uint32) {
+	xl0, xr0 := l0^c0.p[0], r0
+	xl1, xr1 := l1^c1.p[0], r1
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[1]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[1]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[2]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[2]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[3]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[3]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[4]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[4]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[5]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[5]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[6]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[6]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[7]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[7]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[8]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[8]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[9]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[9]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[10]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[10]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[11]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[11]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[12]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[12]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[13]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[13]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[14]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[14]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[15]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[15]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[16]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[16]
+	xr0 ^= c0.p[17]
+	xr1 ^= c1.p[17]
+	return xr0, xl0, xr1, xl1
+}
+
+func encryptBlock4(l0, r0 uint32, c0 *Cipher, l1, r1 uint32, c1 *Cipher, l2, r2 uint32, c2 *Cipher, l3, r3 uint32, c3 *Cipher) (uint32, uint32, uint32, uint32, uint32, uint32, uint32, uint32) {
+	xl0, xr0 := l0^c0.p[0], r0
+	xl1, xr1 := l1^c1.p[0], r1
+	xl2, xr2 := l2^c2.p[0], r2
+	xl3, xr3 := l3^c3.p[0], r3
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[1]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[1]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[1]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[1]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[2]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[2]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[2]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[2]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[3]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[3]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[3]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[3]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[4]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[4]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[4]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[4]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[5]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[5]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[5]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[5]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[6]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[6]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[6]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[6]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[7]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[7]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[7]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[7]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[8]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[8]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[8]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[8]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[9]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[9]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[9]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[9]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[10]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[10]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[10]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[10]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[11]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[11]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[11]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[11]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[12]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[12]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[12]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[12]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[13]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[13]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[13]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[13]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[14]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[14]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[14]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[14]
+	xr0 ^= ((c0.s0[byte(xl0>>24)] + c0.s1[byte(xl0>>16)]) ^ c0.s2[byte(xl0>>8)]) + c0.s3[byte(xl0)] ^ c0.p[15]
+	xr1 ^= ((c1.s0[byte(xl1>>24)] + c1.s1[byte(xl1>>16)]) ^ c1.s2[byte(xl1>>8)]) + c1.s3[byte(xl1)] ^ c1.p[15]
+	xr2 ^= ((c2.s0[byte(xl2>>24)] + c2.s1[byte(xl2>>16)]) ^ c2.s2[byte(xl2>>8)]) + c2.s3[byte(xl2)] ^ c2.p[15]
+	xr3 ^= ((c3.s0[byte(xl3>>24)] + c3.s1[byte(xl3>>16)]) ^ c3.s2[byte(xl3>>8)]) + c3.s3[byte(xl3)] ^ c3.p[15]
+	xl0 ^= ((c0.s0[byte(xr0>>24)] + c0.s1[byte(xr0>>16)]) ^ c0.s2[byte(xr0>>8)]) + c0.s3[byte(xr0)] ^ c0.p[16]
+	xl1 ^= ((c1.s0[byte(xr1>>24)] + c1.s1[byte(xr1>>16)]) ^ c1.s2[byte(xr1>>8)]) + c1.s3[byte(xr1)] ^ c1.p[16]
+	xl2 ^= ((c2.s0[byte(xr2>>24)] + c2.s1[byte(xr2>>16)]) ^ c2.s2[byte(xr2>>8)]) + c2.s3[byte(xr2)] ^ c2.p[16]
+	xl3 ^= ((c3.s0[byte(xr3>>24)] + c3.s1[byte(xr3>>16)]) ^ c3.s2[byte(xr3>>8)]) + c3.s3[byte(xr3)] ^ c3.p[16]
+	xr0 ^= c0.p[17]
+	xr1 ^= c1.p[17]
+	xr2 ^= c2.p[17]
+	xr3 ^= c3.p[17]
+	return xr0, xl0, xr1, xl1, xr2, xl2, xr3, xl3
+}
@@ -0,0 +1,159 @@
+package battcrypt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCalibrationBudget is returned by Calibrate when even the lowest costs do
+// not fit in the requested time or memory.
+var ErrCalibrationBudget = errors.New("battcrypt: calibration budget is too small for the lowest costs")
+
+// minSample is the shortest period measure will time. Cheaper costs are
+// repeated until they take at least this long.
+const minSample = 10 * time.Millisecond
+
+// Calibrate benchmarks the hash on the current machine and returns the
+// highest costs that take at most target to compute and use at most
+// maxMemoryBytes of memory, as reported by Params.MemoryBytes.
+//
+// Memory is raised first, as far as the budget and the limit set by
+// SetMemoryLimit allow, and only then time. Upgrade is always left at 0 so
+// that Strengthen can raise it later.
+func Calibrate(target time.Duration, maxMemoryBytes uint64) (Params, error) {
+	var p Params
+	if p.MemoryBytes() > maxMemoryBytes {
+		return p, ErrCalibrationBudget
+	}
+	d, err := measure(p)
+	if err != nil {
+		return p, err
+	}
+	if d > target {
+		return p, ErrCalibrationBudget
+	}
+
+	// Each memory cost doubles the work, so stop as soon as the next one is
+	// expected to go over budget.
+	for p.Memory < MaxMemory && 2*d <= target {
+		next := p
+		next.Memory++
+		if next.MemoryBytes() > maxMemoryBytes {
+			break
+		}
+		nd, err := measure(next)
+		if errors.Is(err, ErrMemoryLimit) {
+			break
+		}
+		if err != nil {
+			return p, err
+		}
+		if nd > target {
+			break
+		}
+		p, d = next, nd
+	}
+
+	// The work of each upgrade iteration is proportional to the number of
+	// main loop passes plus the pass that initializes memory.
+	work := func(p Params) time.Duration {
+		return time.Duration(p.MainIterations() + 1)
+	}
+	for p.Time < MaxTime {
+		next := p
+		next.Time++
+		if d/work(p)*work(next) > target {
+			break
+		}
+		nd, err := measure(next)
+		if err != nil {
+			return p, err
+		}
+		if nd > target {
+			break
+		}
+		p, d = next, nd
+	}
+
+	return p, nil
+}
+
+// measure returns the average time taken to compute a hash with p.
+func measure(p Params) (time.Duration, error) {
+	var total time.Duration
+	var n time.Duration
+	for total < minSample {
+		start := time.Now()
+		if _, err := BATTCrypt(nil, nil, p.Time, p.Upgrade, p.Memory); err != nil {
+			return 0, err
+		}
+		total += time.Since(start)
+		n++
+	}
+	return total / n, nil
+}
+
+// calibration is the format of the file written by CalibrateFile.
+type calibration struct {
+	Target         time.Duration
+	MaxMemoryBytes uint64
+	Params         Params
+}
+
+// valid reports whether c.Params could have been returned by Calibrate for
+// c's budget, so that an edited or corrupt file is not trusted.
+func (c *calibration) valid() bool {
+	p := c.Params
+	return p.Validate() == nil && p.Upgrade == 0 && p.Mode == ModeDependent && p.Lanes <= 1 &&
+		p.MemoryBytes() <= c.MaxMemoryBytes
+}
+
+// CalibrateFile is like Calibrate, but remembers its result in the file at
+// path. If the file already holds a result for the same target and memory
+// budget, it is returned without running the benchmark again. Results that
+// Calibrate could not have produced are ignored.
+func CalibrateFile(path string, target time.Duration, maxMemoryBytes uint64) (Params, error) {
+	var c calibration
+	if b, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(b, &c) == nil && c.Target == target && c.MaxMemoryBytes == maxMemoryBytes && c.valid() {
+			return c.Params, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return Params{}, err
+	}
+
+	p, err := Calibrate(target, maxMemoryBytes)
+	if err != nil {
+		return p, err
+	}
+
+	b, err := json.Marshal(calibration{
+		Target:         target,
+		MaxMemoryBytes: maxMemoryBytes,
+		Params:         p,
+	})
+	if err != nil {
+		return p, err
+	}
+
+	// Write to a temporary file first so that a concurrent reader never
+	// sees a partial result.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return p, err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return p, err
+}
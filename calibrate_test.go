@@ -0,0 +1,76 @@
+package battcrypt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+	const target = 20 * time.Millisecond
+	maxMemory := Params{Memory: 3}.MemoryBytes()
+
+	p, err := Calibrate(target, maxMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Upgrade != 0 {
+		t.Errorf("upgrade cost %d != 0", p.Upgrade)
+	}
+	if p.MemoryBytes() > maxMemory {
+		t.Errorf("%v uses %d bytes, more than %d", p, p.MemoryBytes(), maxMemory)
+	}
+
+	if _, err := Calibrate(target, Params{}.MemoryBytes()-1); err != ErrCalibrationBudget {
+		t.Errorf("memory budget below minimum: %v", err)
+	}
+	if _, err := Calibrate(0, maxMemory); err != ErrCalibrationBudget {
+		t.Errorf("time budget below minimum: %v", err)
+	}
+}
+
+func TestCalibrateFile(t *testing.T) {
+	const target = 20 * time.Millisecond
+	maxMemory := Params{Memory: 2}.MemoryBytes()
+	path := filepath.Join(t.TempDir(), "battcrypt.json")
+
+	p, err := CalibrateFile(path, target, maxMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := CalibrateFile(path, target, maxMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != p {
+		t.Errorf("cached %v != %v", again, p)
+	}
+
+	// A stored result is trusted as long as the budget matches. m=1 takes
+	// 18432 bytes.
+	if err := os.WriteFile(path, []byte(`{"Target":20000000,"MaxMemoryBytes":18432,"Params":"t=7,u=0,m=1"}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := CalibrateFile(path, target, 18432); err != nil || p != (Params{Time: 7, Memory: 1}) {
+		t.Errorf("stored result not used: %v, %v", p, err)
+	}
+
+	// Costs Calibrate would not have chosen are measured again.
+	for _, params := range []string{"t=62,u=0,m=1,p=2", "t=7,u=0,m=1,a=1", "t=7,u=1,m=1", "t=7,u=0,m=5"} {
+		if err := os.WriteFile(path, []byte(`{"Target":20000000,"MaxMemoryBytes":18432,"Params":"`+params+`"}`), 0666); err != nil {
+			t.Fatal(err)
+		}
+		p, err := CalibrateFile(path, target, 18432)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.String() == params || p.MemoryBytes() > 18432 {
+			t.Errorf("stored result %s used: %v", params, p)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package battcrypt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContextMatches(t *testing.T) {
+	expected, err := BATTCrypt(xkcd, salt, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := BATTCryptContext(context.Background(), xkcd, salt, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != expected {
+		t.Errorf("%x != %x", key, expected)
+	}
+
+	old, err := BATTCrypt(xkcd, salt, 1, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err = Strengthen(old, 1, 0, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = StrengthenContext(context.Background(), old, 1, 0, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != expected {
+		t.Errorf("%x != %x", key, expected)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if key, err := BATTCryptContext(ctx, xkcd, salt, 0, 0, 0); err != context.Canceled || key != [64]byte{} {
+		t.Errorf("BATTCryptContext: %x, %v", key, err)
+	}
+	if key, err := StrengthenContext(ctx, [64]byte{1}, 0, 0, 1, 0); err != context.Canceled || key != [64]byte{} {
+		t.Errorf("StrengthenContext: %x, %v", key, err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	// Takes several seconds if it is not interrupted.
+	key, err := BATTCryptContext(ctx, xkcd, salt, 10, 4, 12)
+	elapsed := time.Since(start)
+
+	if err != context.DeadlineExceeded || key != [64]byte{} {
+		t.Errorf("%x, %v", key, err)
+	}
+	if elapsed > timeout+time.Second {
+		t.Errorf("took %v to notice the deadline", elapsed)
+	}
+}
@@ -0,0 +1,282 @@
+package battcrypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Version is the algorithm version written by GenerateFromPassword. Hashes
+// with later versions are rejected by ParseHash.
+//
+// Version 0 hashes were written by earlier releases of this package, whose
+// Strengthen performed one upgrade iteration too many each time it was
+// called. They are checked as described for Hash.ExtraIterations.
+const Version = 1
+
+// SaltSize is the length of the random salt chosen by GenerateFromPassword.
+const SaltSize = 16
+
+const hashPrefix = "$battcrypt$"
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// ErrMismatchedHashAndPassword is returned by CompareHashAndPassword when the
+// password does not match the hash.
+var ErrMismatchedHashAndPassword = errors.New("battcrypt: hashed password is not the hash of the given password")
+
+// InvalidHashPrefixError is returned when an encoded hash does not start with
+// "$battcrypt$". Its value is the beginning of the offending string.
+type InvalidHashPrefixError string
+
+func (e InvalidHashPrefixError) Error() string {
+	return "battcrypt: encoded hash does not start with " + strconv.Quote(hashPrefix) + ": " + strconv.Quote(string(e))
+}
+
+// UnsupportedVersionError is returned when an encoded hash was produced by a
+// version of the algorithm that this package does not implement.
+type UnsupportedVersionError uint64
+
+func (e UnsupportedVersionError) Error() string {
+	return "battcrypt: unsupported hash version " + strconv.FormatUint(uint64(e), 10)
+}
+
+// MalformedHashError is returned when a field of an encoded hash cannot be
+// parsed. Its value names the field.
+type MalformedHashError string
+
+func (e MalformedHashError) Error() string {
+	return "battcrypt: malformed " + string(e) + " in encoded hash"
+}
+
+// Hash is the parsed form of an encoded hash string:
+//
+//	$battcrypt$v=1$t=1,u=0,m=4$<salt>$<key>
+//
+// The salt and key are base64 encoded without padding. Extra iterations,
+// which only version 0 hashes can have, and the ID of the pepper used by a
+// PepperedHasher, if any, follow the costs:
+//
+//	$battcrypt$v=0$t=1,u=1,m=4,x=1,k=<key ID>$<salt>$<key>
+type Hash struct {
+	Version uint64
+	Params  Params
+
+	// ExtraIterations is the number of upgrade iterations performed in
+	// addition to those required by Params.Upgrade, for a version 0 hash
+	// strengthened by an earlier release of this package, which performed
+	// one extra iteration each time Strengthen was called. Each of those
+	// calls raised the upgrade cost, so there can be at most
+	// Params.Upgrade extra iterations, and ParseHash rejects more.
+	//
+	// If it is 0 for a version 0 hash, the number is not known, and the
+	// hash is checked after each of up to Params.Upgrade extra
+	// iterations. A wrong password then costs up to Params.Upgrade more
+	// iterations to reject. Such hashes should be replaced with ones from
+	// GenerateFromPassword once the password has been checked.
+	ExtraIterations uint64
+
+	KeyID string
+	Salt  []byte
+	Key   [64]byte
+}
+
+// String returns the encoded form of h.
+func (h *Hash) String() string {
+	var extra, keyID string
+	if h.ExtraIterations != 0 {
+		extra = ",x=" + strconv.FormatUint(h.ExtraIterations, 10)
+	}
+	if h.KeyID != "" {
+		keyID = ",k=" + h.KeyID
+	}
+	return hashPrefix + "v=" + strconv.FormatUint(h.Version, 10) +
+		"$" + h.Params.String() + extra + keyID +
+		"$" + b64.EncodeToString(h.Salt) +
+		"$" + b64.EncodeToString(h.Key[:])
+}
+
+// ParseHash decodes an encoded hash string. The costs are checked with the
+// same rules as BATTCrypt.
+func ParseHash(encoded string) (*Hash, error) {
+	if !strings.HasPrefix(encoded, hashPrefix) {
+		prefix := encoded
+		if len(prefix) > len(hashPrefix) {
+			prefix = prefix[:len(hashPrefix)]
+		}
+		return nil, InvalidHashPrefixError(prefix)
+	}
+
+	fields := strings.Split(encoded[len(hashPrefix):], "$")
+	if len(fields) != 4 {
+		return nil, MalformedHashError("field count")
+	}
+
+	var h Hash
+	var ok bool
+
+	if h.Version, ok = parseField(fields[0], "v="); !ok {
+		return nil, MalformedHashError("version")
+	}
+	if h.Version > Version {
+		return nil, UnsupportedVersionError(h.Version)
+	}
+
+	if i := strings.Index(fields[1], ",k="); i != -1 {
+		h.KeyID = fields[1][i+len(",k="):]
+		fields[1] = fields[1][:i]
+		if !validKeyID(h.KeyID) {
+			return nil, MalformedHashError("key ID")
+		}
+	}
+
+	if i := strings.Index(fields[1], ",x="); i != -1 {
+		h.ExtraIterations, ok = parseField(fields[1][i+1:], "x=")
+		if !ok || h.Version != 0 || h.ExtraIterations == 0 {
+			return nil, MalformedHashError("extra iterations")
+		}
+		fields[1] = fields[1][:i]
+	}
+
+	params, err := ParseParams(fields[1])
+	if err == ErrParamsSyntax {
+		return nil, MalformedHashError("costs")
+	} else if err != nil {
+		return nil, err
+	}
+	h.Params = params
+
+	// Each call to the old Strengthen raised the upgrade cost by at least
+	// one, so there cannot be more extra iterations than that.
+	if h.ExtraIterations > h.Params.Upgrade {
+		return nil, MalformedHashError("extra iterations")
+	}
+
+	// The decoder skips line breaks, which would give a hash more than
+	// one encoding.
+	if strings.ContainsAny(fields[2], "\r\n") {
+		return nil, MalformedHashError("salt")
+	}
+	if strings.ContainsAny(fields[3], "\r\n") {
+		return nil, MalformedHashError("key")
+	}
+
+	salt, err := b64.DecodeString(fields[2])
+	if err != nil {
+		return nil, MalformedHashError("salt")
+	}
+	h.Salt = salt
+
+	key, err := b64.DecodeString(fields[3])
+	if err != nil || len(key) != len(h.Key) {
+		return nil, MalformedHashError("key")
+	}
+	copy(h.Key[:], key)
+
+	return &h, nil
+}
+
+// newHash returns a Hash with a random salt.
+func newHash(params Params, keyID string) (*Hash, error) {
+	h := &Hash{
+		Version: Version,
+		Params:  params,
+		KeyID:   keyID,
+		Salt:    make([]byte, SaltSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, h.Salt); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// GenerateFromPassword hashes password with a random salt and returns the
+// encoded hash, which records everything needed to verify it later.
+func GenerateFromPassword(password []byte, params Params) (string, error) {
+	return GenerateFromPasswordWithOptions(password, params, nil)
+}
+
+// GenerateFromPasswordWithOptions is like GenerateFromPassword, but accepts
+// optional settings. Options.AssociatedData is not recorded in the encoded
+// hash; it must be passed again to CompareHashAndPasswordWithOptions.
+func GenerateFromPasswordWithOptions(password []byte, params Params, opts *Options) (string, error) {
+	h, err := newHash(params, "")
+	if err != nil {
+		return "", err
+	}
+
+	key, err := BATTCryptWithOptions(password, h.Salt, params, opts)
+	if err != nil {
+		return "", err
+	}
+	h.Key = key
+
+	return h.String(), nil
+}
+
+// CompareHashAndPassword compares an encoded hash with a possible plaintext
+// equivalent. It returns nil on success, ErrMismatchedHashAndPassword if the
+// password is wrong, or an error describing why the hash could not be used.
+// Peppered hashes must be checked with PepperedHasher instead.
+func CompareHashAndPassword(encoded string, password []byte) error {
+	return CompareHashAndPasswordWithOptions(encoded, password, nil)
+}
+
+// CompareHashAndPasswordWithOptions is like CompareHashAndPassword, but
+// accepts optional settings.
+func CompareHashAndPasswordWithOptions(encoded string, password []byte, opts *Options) error {
+	h, err := ParseHash(encoded)
+	if err != nil {
+		return err
+	}
+	if h.KeyID != "" {
+		return ErrPeppered
+	}
+	return h.compare(password, opts)
+}
+
+// compare checks password against h without regard to h.KeyID.
+func (h *Hash) compare(password []byte, opts *Options) error {
+	if h.Version == 0 && h.ExtraIterations == 0 && h.Params.Upgrade != 0 {
+		ok, err := matchLegacy(password, h.Salt, h.Params, h.Key, h.Params.Upgrade, opts)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrMismatchedHashAndPassword
+		}
+		return nil
+	}
+
+	key, err := hashPassword(password, h.Salt, h.Params, h.ExtraIterations, opts)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(key[:], h.Key[:]) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// StrengthenHash raises the upgrade cost of an encoded hash to upgrade
+// without needing the password. See Strengthen. A version 0 hash stays at
+// version 0, keeping any extra iterations it has.
+func StrengthenHash(encoded string, upgrade uint64) (string, error) {
+	h, err := ParseHash(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	h.Key, err = StrengthenWithOptions(h.Key, h.Params, upgrade, nil)
+	if err != nil {
+		return "", err
+	}
+	h.Params.Upgrade = upgrade
+
+	return h.String(), nil
+}
@@ -0,0 +1,111 @@
+package battcrypt
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAndCompare(t *testing.T) {
+	params := Params{Time: 1, Upgrade: 1, Memory: 1}
+
+	encoded, err := GenerateFromPassword(xkcd, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := ParseHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != Version || h.Params != params || len(h.Salt) != SaltSize {
+		t.Errorf("unexpected parse of %q: %#v", encoded, h)
+	}
+	if s := h.String(); s != encoded {
+		t.Errorf("%q != %q", s, encoded)
+	}
+
+	if err := CompareHashAndPassword(encoded, xkcd); err != nil {
+		t.Errorf("correct password: %v", err)
+	}
+	if err := CompareHashAndPassword(encoded, []byte("Tr0ub4dor&3")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("wrong password: %v", err)
+	}
+}
+
+func TestCompareKnownHash(t *testing.T) {
+	key, _ := hex.DecodeString("a6ddd44ec442a4efa2b040ecdfe55faba23a868b62f975bab4231ab6055e4012b6a067bab54da6473514d662f3323a22778570a0a7734ac151dd4d1f80c39bbb")
+	const encoded = "$battcrypt$v=0$t=1,u=0,m=0$c2FsdA$pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw"
+
+	h, err := ParseHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(h.Salt) != "salt" || string(h.Key[:]) != string(key) {
+		t.Errorf("unexpected parse of %q: %#v", encoded, h)
+	}
+
+	if err := CompareHashAndPassword(encoded, []byte("password")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseHashErrors(t *testing.T) {
+	const key = "pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw"
+
+	for _, test := range []struct {
+		Encoded string
+		Err     error
+	}{
+		{"", InvalidHashPrefixError("")},
+		{"$2a$10$abc", InvalidHashPrefixError("$2a$10$abc")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdA", MalformedHashError("field count")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdA$" + key + "$", MalformedHashError("field count")},
+		{"$battcrypt$v=00$t=1,u=0,m=0$c2FsdA$" + key, MalformedHashError("version")},
+		{"$battcrypt$v=2$t=1,u=0,m=0$c2FsdA$" + key, UnsupportedVersionError(2)},
+		{"$battcrypt$v=0$m=0,t=1,u=0$c2FsdA$" + key, MalformedHashError("costs")},
+		{"$battcrypt$v=0$t=+1,u=0,m=0$c2FsdA$" + key, MalformedHashError("costs")},
+		{"$battcrypt$v=0$t=1,u=0,m=51$c2FsdA$" + key, ErrCostRange},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdA==$" + key, MalformedHashError("salt")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdB$" + key, MalformedHashError("salt")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdA$" + key[:80], MalformedHashError("key")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2Fs\ndA$" + key, MalformedHashError("salt")},
+		{"$battcrypt$v=0$t=1,u=0,m=0$c2FsdA$" + key[:40] + "\r\n" + key[40:], MalformedHashError("key")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,k=$c2FsdA$" + key, MalformedHashError("key ID")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,x=0$c2FsdA$" + key, MalformedHashError("extra iterations")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,x=01$c2FsdA$" + key, MalformedHashError("extra iterations")},
+		{"$battcrypt$v=0$t=0,u=0,m=0,x=4294967296$c2FsdA$" + key, MalformedHashError("extra iterations")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,x=1$c2FsdA$" + key, MalformedHashError("extra iterations")},
+		{"$battcrypt$v=0$t=1,u=2,m=0,x=3$c2FsdA$" + key, MalformedHashError("extra iterations")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,k=a,x=1$c2FsdA$" + key, MalformedHashError("key ID")},
+		{"$battcrypt$v=0$t=1,u=0,m=0,k=a/b$c2FsdA$" + key, MalformedHashError("key ID")},
+		{"$battcrypt$v=0$t=1,k=a,u=0,m=0$c2FsdA$" + key, MalformedHashError("key ID")},
+	} {
+		if _, err := ParseHash(test.Encoded); err != test.Err {
+			t.Errorf("ParseHash(%q): %v != %v", test.Encoded, err, test.Err)
+		}
+		if err := CompareHashAndPassword(test.Encoded, nil); err != test.Err {
+			t.Errorf("CompareHashAndPassword(%q): %v != %v", test.Encoded, err, test.Err)
+		}
+	}
+}
+
+func FuzzParseHash(f *testing.F) {
+	f.Add("$battcrypt$v=0$t=1,u=0,m=0$c2FsdA$pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw")
+	f.Add("$battcrypt$v=0$t=62,u=63,m=50$$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	f.Add("$battcrypt$v=1$t=0,u=0,m=0$$")
+	f.Add("$battcrypt$v=0$t=1,u=2,m=0,x=2,k=a$c2FsdA$pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw")
+	f.Add("$battcrypt$v=0$t=1,u=0,m=0,k=2024-01$c2FsdA$pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw")
+	f.Add("$battcrypt$v=0$t=0,u=0,m=0$$" + strings.Repeat("0", 76) + "\n000000000A")
+	f.Add("$battcrypt$v=0$t=1,u=0,m=0,a=2,x=1$c2FsdA$pt3UTsRCpO+isEDs3+Vfq6I6hoti+XW6tCMatgVeQBK2oGe6tU2mRzUU1mLzMjoid4VwoKdzSsFR3U0fgMObuw")
+
+	f.Fuzz(func(t *testing.T, encoded string) {
+		h, err := ParseHash(encoded)
+		if err != nil {
+			return
+		}
+		if s := h.String(); s != encoded {
+			t.Fatalf("ParseHash(%q).String() = %q", encoded, s)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package battcrypt
+
+import "testing"
+
+// unfuse makes h use the separate steps in the main loop.
+func unfuse(h *Hasher) {
+	h.fused = nil
+	for _, l := range h.lanes {
+		l.fused = nil
+	}
+}
+
+func TestFusedKernel(t *testing.T) {
+	if NewHasher().fused == nil {
+		t.Skip("CBC mode does not support CryptBlocksXOR")
+	}
+
+	for _, p := range []Params{
+		{Time: 0, Upgrade: 0, Memory: 0},
+		{Time: 2, Upgrade: 2, Memory: 3},
+		{Time: 1, Upgrade: 1, Memory: 5, Mode: ModeHybrid},
+		{Time: 1, Upgrade: 0, Memory: 4, Lanes: 4},
+	} {
+		for _, password := range []string{"", "password", "correct horse battery staple"} {
+			key, err := NewHasher().BATTCrypt([]byte(password), salt, p, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := NewHasher()
+			if p.Lanes > 1 {
+				// Create the other lanes before unfusing them.
+				if _, err := h.BATTCrypt(nil, nil, p, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			unfuse(h)
+			expected, err := h.BATTCrypt([]byte(password), salt, p, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != expected {
+				t.Errorf("%q %v: fused %x, unfused %x", password, p, key, expected)
+			}
+		}
+	}
+}
+
+func doFusedBenchmark(b *testing.B, memory uint64, fused bool) {
+	p := Params{Time: 0, Upgrade: 0, Memory: memory}
+	h := NewHasher()
+	if !fused {
+		unfuse(h)
+	}
+	b.SetBytes(int64(p.MemoryBytes()))
+	for i := 0; i < b.N; i++ {
+		if _, err := h.BATTCrypt(xkcd, salt, p, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFusedM8(b *testing.B)    { doFusedBenchmark(b, 8, true) }
+func BenchmarkFusedM12(b *testing.B)   { doFusedBenchmark(b, 12, true) }
+func BenchmarkFusedM14(b *testing.B)   { doFusedBenchmark(b, 14, true) }
+func BenchmarkUnfusedM8(b *testing.B)  { doFusedBenchmark(b, 8, false) }
+func BenchmarkUnfusedM12(b *testing.B) { doFusedBenchmark(b, 12, false) }
+func BenchmarkUnfusedM14(b *testing.B) { doFusedBenchmark(b, 14, false) }
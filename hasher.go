@@ -0,0 +1,597 @@
+package battcrypt
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/BenLubar/battcrypt/blowfish"
+)
+
+// A Hasher holds the working memory needed to compute hashes and keeps it
+// between calls, so that computing many hashes with the same memory cost
+// does not allocate. The memory, the Blowfish key schedule, and the SHA-512
+// state are wiped after each call.
+//
+// A Hasher must not be used by more than one goroutine at a time. The
+// package-level functions share a pool of Hashers, so most programs do not
+// need to create their own.
+type Hasher struct {
+	// The Hasher's own state is that of its first lane. Any other lanes
+	// are created as needed and kept for later calls.
+	lane
+	lanes []*lane
+
+	// slab holds the memory blocks of every lane followed by the data
+	// block of every lane. Its capacity is kept between calls, as is that
+	// of blocks, which holds the memory blocks of every lane.
+	slab   []byte
+	blocks [][]byte
+
+	// locked is set for Hashers from NewLockedHasher. slabMapped is set
+	// if slab came from mapLocked. slabHuge is set if slab came from
+	// mapHuge; such a slab is released at the end of each call.
+	locked     bool
+	slabMapped bool
+	slabHuge   bool
+
+	// barrier is where the lanes wait for each other.
+	barrier barrier
+
+	// The remaining fields only apply to the call in progress.
+	t_cost_main uint64
+	seg_size    uint64 // blocks in each lane, a power of two
+	mode        Mode
+
+	ctx      context.Context
+	done     <-chan struct{}
+	progress func(completed, total uint64)
+
+	completed uint64 // updated atomically
+	reported  uint64
+	total     uint64
+	step      uint64
+}
+
+// A lane holds the state needed to fill one segment of memory.
+type lane struct {
+	sha  hash.Hash
+	blow *blowfish.Cipher
+	cbc  cipher.BlockMode
+
+	// fused is cbc if it can do the main loop's step in one pass.
+	fused blocksXORer
+
+	// blowMap holds the mapping that blow lives in, if any.
+	blowMap []byte
+
+	data []byte
+	mem  [][]byte
+
+	// key and scratch are kept here rather than on the stack because
+	// they are passed to sha through an interface.
+	key     [64]byte
+	scratch [8]byte
+
+	// indices holds the next eight block indices for the data-independent
+	// modes.
+	indices [64]byte
+
+	// index is the lane's number, and the position of its segment of
+	// memory.
+	index uint64
+
+	// pending counts blocks processed since the last flush.
+	pending uint64
+}
+
+const maxInt = uint64(^uint(0) >> 1)
+
+type ivSetter interface {
+	SetIV([]byte)
+}
+
+type blocksXORer interface {
+	CryptBlocksXOR(dst, src, acc []byte)
+}
+
+// NewHasher returns a Hasher with no working memory. Memory is allocated by
+// the first call that needs it.
+func NewHasher() *Hasher {
+	h := &Hasher{}
+	h.lane.init(false)
+	return h
+}
+
+// NewLockedHasher returns a Hasher that keeps its working memory and
+// Blowfish key schedule outside the Go heap, locked into RAM so that they
+// are never written to swap, and left out of core dumps. If the memory
+// cannot be locked, for example because RLIMIT_MEMLOCK is too low, or the
+// system is not Linux, ordinary memory is used instead.
+//
+// The memory is released when the Hasher is garbage collected, or earlier
+// by calling Close.
+func NewLockedHasher() *Hasher {
+	h := &Hasher{locked: true}
+	h.lane.init(true)
+	runtime.SetFinalizer(h, (*Hasher).Close)
+	return h
+}
+
+// init sets up the hash functions of l. If locked is set, the Blowfish key
+// schedule is kept in locked memory if possible.
+func (l *lane) init(locked bool) {
+	l.sha = sha512.New()
+	if !locked {
+		l.blow = new(blowfish.Cipher)
+	} else if b, err := mapSlab(int(unsafe.Sizeof(blowfish.Cipher{}))); err == nil {
+		// Cipher holds no pointers, so it may live outside the heap.
+		l.blowMap = b
+		l.blow = (*blowfish.Cipher)(unsafe.Pointer(&b[0]))
+	} else {
+		l.blow = new(blowfish.Cipher)
+	}
+	if err := l.blow.Reset(emptyKey); err != nil {
+		// only possible error is invalid key size
+		panic(err)
+	}
+	l.cbc = cipher.NewCBCEncrypter(l.blow, emptyIV)
+	l.fused, _ = l.cbc.(blocksXORer)
+}
+
+// close releases the locked memory held by l, if any.
+func (l *lane) close() {
+	if l.blowMap != nil {
+		l.blow = nil
+		l.cbc = nil
+		l.fused = nil
+		unmapLocked(l.blowMap)
+		l.blowMap = nil
+	}
+}
+
+// mapSlab is replaced by tests to simulate a low RLIMIT_MEMLOCK.
+var mapSlab = mapLocked
+
+// hugeThreshold is the smallest slab, in bytes, that is mapped with mapHuge
+// rather than allocated on the Go heap. Slabs this large benefit from huge
+// pages, and would otherwise stay in the heap long after the hash is done.
+// It is a variable so that benchmarks can compare both paths.
+var hugeThreshold uint64 = 32 << 20
+
+// Close releases memory held by a Hasher from NewLockedHasher. The Hasher
+// must not be used afterwards. Close does nothing for other Hashers.
+func (h *Hasher) Close() error {
+	if !h.locked {
+		return nil
+	}
+	runtime.SetFinalizer(h, nil)
+
+	h.freeSlab()
+	h.lane.close()
+	for _, l := range h.lanes {
+		l.close()
+	}
+	return nil
+}
+
+// freeSlab lets go of h.slab, unmapping it if necessary.
+func (h *Hasher) freeSlab() {
+	if h.slabMapped {
+		unmapLocked(h.slab)
+		h.slabMapped = false
+	}
+	if h.slabHuge {
+		unmapHuge(h.slab)
+		h.slabHuge = false
+	}
+	h.slab = nil
+	h.blocks = nil
+	h.lane.mem, h.lane.data = nil, nil
+	for _, l := range h.lanes {
+		l.mem, l.data = nil, nil
+	}
+}
+
+// hasherPools and lockedPools hold idle Hashers for the package-level
+// functions, indexed by memory cost.
+var hasherPools, lockedPools [MaxMemory + 1]sync.Pool
+
+func getHasher(memory uint64, opts *Options) *Hasher {
+	if opts.lockMemory() {
+		if h, ok := lockedPools[memory].Get().(*Hasher); ok {
+			return h
+		}
+		return NewLockedHasher()
+	}
+	if h, ok := hasherPools[memory].Get().(*Hasher); ok {
+		return h
+	}
+	return NewHasher()
+}
+
+func putHasher(memory uint64, h *Hasher) {
+	if h.locked {
+		lockedPools[memory].Put(h)
+	} else {
+		hasherPools[memory].Put(h)
+	}
+}
+
+// BATTCrypt is like the package-level BATTCryptWithOptions, but uses h's
+// memory.
+func (h *Hasher) BATTCrypt(password, salt []byte, p Params, opts *Options) ([64]byte, error) {
+	if err := p.Validate(); err != nil {
+		return [64]byte{}, err
+	}
+	return h.hashPassword(password, salt, p, 0, opts)
+}
+
+// Strengthen is like the package-level StrengthenWithOptions, but uses h's
+// memory.
+func (h *Hasher) Strengthen(old [64]byte, p Params, upgrade_new uint64, opts *Options) ([64]byte, error) {
+	_, t_cost_upgrade_old, _, _, err := p.layout()
+	if err != nil {
+		return [64]byte{}, err
+	}
+	_, t_cost_upgrade_new, _, err := costs(p.Time, upgrade_new, p.Memory)
+	if err != nil {
+		return [64]byte{}, err
+	}
+	if t_cost_upgrade_old > t_cost_upgrade_new {
+		return [64]byte{}, ErrUpgradeInvalid
+	}
+	if t_cost_upgrade_old == t_cost_upgrade_new {
+		return old, nil
+	}
+	return h.strengthen(old, p, t_cost_upgrade_new-t_cost_upgrade_old, opts)
+}
+
+// hashPassword computes a hash with costs that have already been checked.
+func (h *Hasher) hashPassword(password, salt []byte, p Params, extra uint64, opts *Options) ([64]byte, error) {
+	if err := h.begin(p, opts); err != nil {
+		return [64]byte{}, err
+	}
+	defer h.end()
+
+	h.lane.deriveKey(password, salt, opts.associatedData())
+	h.expect(p.UpgradeIterations() + extra)
+	return h.upgrade(p.UpgradeIterations() + extra)
+}
+
+// matchLegacy is hashPassword for a version 0 hash whose number of extra
+// iterations is not known. It checks the key after each extra iteration,
+// up to maxExtra of them, and reports whether any of them was want. Progress
+// is reported against the most iterations that might be needed.
+func (h *Hasher) matchLegacy(password, salt []byte, p Params, want [64]byte, maxExtra uint64, opts *Options) (bool, error) {
+	if err := h.begin(p, opts); err != nil {
+		return false, err
+	}
+	defer h.end()
+
+	h.lane.deriveKey(password, salt, opts.associatedData())
+	h.expect(p.UpgradeIterations() + maxExtra)
+	key, err := h.upgrade(p.UpgradeIterations())
+	for extra := uint64(0); err == nil; extra++ {
+		if subtle.ConstantTimeCompare(key[:], want[:]) == 1 {
+			// The rest of the work is not needed.
+			h.report(h.total)
+			return true, nil
+		}
+		if extra == maxExtra {
+			return false, nil
+		}
+		key, err = h.upgrade(1)
+	}
+	return false, err
+}
+
+// deriveKey sets l.key to the initial key for password, salt, and
+// associated data ad.
+func (l *lane) deriveKey(password, salt, ad []byte) {
+	sha, key, scratch := l.sha, l.key[:], l.scratch[:]
+
+	sha.Reset()
+	if len(ad) != 0 {
+		// Length prefixes keep the salt and associated data from being
+		// shifted into each other.
+		binary.BigEndian.PutUint64(scratch, uint64(len(salt)))
+		sha.Write(scratch)
+		sha.Write(salt)
+		binary.BigEndian.PutUint64(scratch, uint64(len(ad)))
+		sha.Write(scratch)
+		sha.Write(ad)
+	} else {
+		sha.Write(salt)
+	}
+	sha.Sum(key[:0])
+
+	sha.Reset()
+	sha.Write(key)
+	sha.Write(password)
+	sha.Sum(key[:0])
+}
+
+// strengthen applies iterations more upgrade iterations to old.
+func (h *Hasher) strengthen(old [64]byte, p Params, iterations uint64, opts *Options) ([64]byte, error) {
+	if err := h.begin(p, opts); err != nil {
+		return [64]byte{}, err
+	}
+	defer h.end()
+
+	h.key = old
+	h.expect(iterations)
+	return h.upgrade(iterations)
+}
+
+// begin prepares h for a computation with the already checked costs p, if
+// the memory limits allow it.
+func (h *Hasher) begin(p Params, opts *Options) error {
+	ctx := opts.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t_cost_main, _, seg_size, lanes, _ := p.layout()
+	n := p.MemoryBytes()
+	if err := reserveMemory(n, opts.memoryLimit()); err != nil {
+		return err
+	}
+
+	if uint64(cap(h.slab)) < n {
+		h.freeSlab() // let the old slab go first
+		if h.locked && n <= maxInt {
+			if b, err := mapSlab(int(n)); err == nil {
+				h.slab, h.slabMapped = b, true
+			}
+		} else if n >= hugeThreshold && n <= maxInt {
+			if b, err := mapHuge(int(n)); err == nil {
+				h.slab, h.slabHuge = b, true
+			}
+		}
+		if h.slab == nil {
+			h.slab = make([]byte, n)
+		}
+	}
+	h.slab = h.slab[:n]
+	mem_size := seg_size * lanes
+	if uint64(cap(h.blocks)) < mem_size {
+		h.blocks = make([][]byte, mem_size)
+	}
+	h.blocks = h.blocks[:mem_size]
+	slab := h.slab
+	for i := range h.blocks {
+		h.blocks[i] = slab[:size:size]
+		slab = slab[size:]
+	}
+	for uint64(len(h.lanes)) < lanes-1 {
+		l := new(lane)
+		l.init(h.locked)
+		h.lanes = append(h.lanes, l)
+	}
+	for i := uint64(0); i < lanes; i++ {
+		l := h.getLane(i)
+		l.index = i
+		l.mem = h.blocks[i*seg_size : (i+1)*seg_size]
+		l.data = slab[i*size : (i+1)*size]
+		l.pending = 0
+		l.blow.SetConstantTime(opts.constantTime())
+	}
+
+	h.t_cost_main = t_cost_main
+	h.seg_size = seg_size
+	h.mode = p.Mode
+	h.ctx = ctx
+	h.done = ctx.Done()
+	h.progress = opts.progress()
+	h.completed, h.reported = 0, 0
+	return nil
+}
+
+// getLane returns lane i of h, where lane 0 is h.lane.
+func (h *Hasher) getLane(i uint64) *lane {
+	if i == 0 {
+		return &h.lane
+	}
+	return h.lanes[i-1]
+}
+
+// end wipes the working memory and forgets the call in progress.
+func (h *Hasher) end() {
+	n := uint64(len(h.slab))
+	wipe(h.slab)
+	if h.slabHuge {
+		h.freeSlab()
+	}
+	h.lane.wipe()
+	for _, l := range h.lanes {
+		l.wipe()
+	}
+
+	releaseMemory(n)
+	h.ctx, h.done, h.progress = nil, nil, nil
+}
+
+// wipe overwrites everything l learned during a computation, apart from its
+// memory, which is part of the Hasher's slab.
+func (l *lane) wipe() {
+	l.key = [64]byte{}
+	l.scratch = [8]byte{}
+	l.indices = [64]byte{}
+	l.blow.Wipe()
+	l.cbc.(ivSetter).SetIV(emptyIV)
+	wipeHash(l.sha)
+}
+
+// expect sets the total work reported to the progress function to that of
+// iterations upgrade iterations.
+func (h *Hasher) expect(iterations uint64) {
+	lanes := uint64(len(h.blocks)) / h.seg_size
+	h.total = iterations * h.seg_size * lanes * (h.t_cost_main + 1)
+	h.step = h.total/progressSteps + 1
+}
+
+// upgrade applies battcrypt to h.key iterations times and returns the
+// result. If the context is done first, ctx.Err() is returned. The work is
+// counted towards the total set by expect.
+func (h *Hasher) upgrade(iterations uint64) ([64]byte, error) {
+	lanes := uint64(len(h.blocks)) / h.seg_size
+
+	for u := uint64(0); u < iterations; u++ {
+		var ok bool
+		if lanes == 1 {
+			ok = h.battcrypt(&h.lane, true)
+		} else {
+			ok = h.parallel(lanes)
+		}
+		h.report(atomic.LoadUint64(&h.completed))
+		if !ok {
+			return [64]byte{}, h.ctx.Err()
+		}
+	}
+	return h.key, nil
+}
+
+// parallel performs one upgrade iteration on h.key with more than one lane.
+// Each lane starts from SHA-512 of h.key and its lane number, and runs in
+// its own goroutine, apart from the first, which runs in this one and
+// reports progress. The outputs of the lanes are then combined with
+// SHA-512. A lane that gives up breaks the barrier, so that the others do
+// not wait for it.
+func (h *Hasher) parallel(lanes uint64) bool {
+	// Lane 0 goes last, as its key is h.key.
+	for i := lanes; i > 0; i-- {
+		h.sha.Reset()
+		h.sha.Write(h.key[:])
+		binary.BigEndian.PutUint64(h.scratch[:], i-1)
+		h.sha.Write(h.scratch[:])
+		h.sha.Sum(h.getLane(i - 1).key[:0])
+	}
+
+	h.barrier.reset(int(lanes))
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	for _, l := range h.lanes[:lanes-1] {
+		wg.Add(1)
+		go func(l *lane) {
+			defer wg.Done()
+			if !h.battcrypt(l, false) {
+				failed.Store(true)
+				h.barrier.abort()
+			}
+		}(l)
+	}
+	ok := h.battcrypt(&h.lane, true)
+	if !ok {
+		h.barrier.abort()
+	}
+	wg.Wait()
+	if !ok || failed.Load() {
+		return false
+	}
+
+	h.sha.Reset()
+	for i := uint64(0); i < lanes; i++ {
+		h.sha.Write(h.getLane(i).key[:])
+	}
+	h.sha.Sum(h.key[:0])
+	return true
+}
+
+// A barrier is where the lanes of a Hasher wait for each other.
+type barrier struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	n       int
+	waiting int
+	round   uint64
+	broken  bool
+}
+
+// reset prepares b for n lanes.
+func (b *barrier) reset(n int) {
+	if b.cond == nil {
+		b.cond = sync.NewCond(&b.mu)
+	}
+	b.n, b.waiting, b.broken = n, 0, false
+}
+
+// wait blocks until all of the lanes have called wait. It returns false if
+// the barrier is broken first.
+func (b *barrier) wait() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.broken {
+		return false
+	}
+	b.waiting++
+	if b.waiting == b.n {
+		b.waiting = 0
+		b.round++
+		b.cond.Broadcast()
+		return true
+	}
+	for round := b.round; round == b.round && !b.broken; {
+		b.cond.Wait()
+	}
+	return !b.broken
+}
+
+// abort breaks b, releasing every lane that is waiting or will wait.
+func (b *barrier) abort() {
+	b.mu.Lock()
+	b.broken = true
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// tick records that l has processed a block. It returns false if the
+// computation has been canceled.
+func (h *Hasher) tick(l *lane, reports bool) bool {
+	l.pending++
+	if l.pending < checkInterval {
+		return true
+	}
+	return h.flush(l, reports)
+}
+
+// flush adds the work pending in l to the total, reports progress if
+// reports is set, and returns false if the computation has been canceled.
+func (h *Hasher) flush(l *lane, reports bool) bool {
+	completed := atomic.AddUint64(&h.completed, l.pending)
+	l.pending = 0
+	if reports {
+		h.report(completed)
+	}
+	return !canceled(h.done)
+}
+
+// report calls the progress function if enough work has been completed
+// since it was last called. It is only called from the goroutine that
+// called begin.
+func (h *Hasher) report(completed uint64) {
+	if h.progress != nil && completed != h.reported && (completed-h.reported >= h.step || completed == h.total) {
+		h.reported = completed
+		h.progress(completed, h.total)
+	}
+}
+
+var zeroBlock [sha512.BlockSize]byte
+
+// wipeHash resets sha and overwrites its internal buffer, which Reset leaves
+// alone. The buffer is filled by writing one byte short of a block and then
+// the final byte, so that none of it is skipped.
+func wipeHash(sha hash.Hash) {
+	sha.Reset()
+	sha.Write(zeroBlock[:sha.BlockSize()-1])
+	sha.Write(zeroBlock[:1])
+	sha.Reset()
+}
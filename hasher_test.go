@@ -0,0 +1,60 @@
+package battcrypt
+
+import "testing"
+
+func TestHasherReuse(t *testing.T) {
+	h := NewHasher()
+	// Shrinking and growing the memory cost must not leave anything
+	// behind that changes the result.
+	for _, p := range []Params{{Time: 1, Upgrade: 1, Memory: 2}, {Time: 0, Upgrade: 0, Memory: 0}, {Time: 2, Upgrade: 0, Memory: 3}, {Time: 1, Upgrade: 1, Memory: 2}} {
+		key, err := h.BATTCrypt(xkcd, salt, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := BATTCrypt(xkcd, salt, p.Time, p.Upgrade, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%v: %x != %x", p, key, expected)
+		}
+
+		strengthened, err := h.Strengthen(key, p, p.Upgrade+1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err = BATTCrypt(xkcd, salt, p.Time, p.Upgrade+1, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strengthened != expected {
+			t.Errorf("%v strengthened: %x != %x", p, strengthened, expected)
+		}
+	}
+}
+
+func TestHasherAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping allocation count in short mode")
+	}
+
+	h := NewHasher()
+	p := Params{Time: 0, Upgrade: 0, Memory: 1}
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := h.BATTCrypt(xkcd, salt, p, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Hasher.BATTCrypt: %v allocations per call", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(10, func() {
+		if _, err := BATTCrypt(xkcd, salt, p.Time, p.Upgrade, p.Memory); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("BATTCrypt: %v allocations per call", allocs)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package battcrypt
+
+import "errors"
+
+var errHugeUnsupported = errors.New("battcrypt: huge pages are not supported on this system")
+
+// mapHuge always fails on systems other than Linux, so the Go heap is used
+// instead.
+func mapHuge(n int) ([]byte, error) {
+	return nil, errHugeUnsupported
+}
+
+func unmapHuge(b []byte) {
+	panic("battcrypt: unmapHuge called without mapHuge")
+}
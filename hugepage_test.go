@@ -0,0 +1,108 @@
+package battcrypt
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestHugeSlab(t *testing.T) {
+	if b, err := mapHuge(size); err != nil {
+		t.Skipf("cannot map memory: %v", err)
+	} else {
+		unmapHuge(b)
+	}
+
+	defer func(orig uint64) { hugeThreshold = orig }(hugeThreshold)
+	hugeThreshold = 0
+
+	h := NewHasher()
+	for _, p := range []Params{{Time: 1, Upgrade: 1, Memory: 2}, {Time: 0, Upgrade: 0, Memory: 4}} {
+		key, err := h.BATTCrypt(xkcd, salt, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hugeThreshold = 1 << 63
+		expected, err := NewHasher().BATTCrypt(xkcd, salt, p, nil)
+		hugeThreshold = 0
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%v: %x != %x", p, key, expected)
+		}
+		if h.slab != nil || h.slabHuge {
+			t.Errorf("%v: mapped slab was not released", p)
+		}
+	}
+	memory.Lock()
+	inUse := memory.inUse
+	memory.Unlock()
+	if inUse != 0 {
+		t.Errorf("%d bytes still reserved", inUse)
+	}
+}
+
+// residentBytes returns the resident set size of the process, or -1 if it
+// is not known.
+func residentBytes() float64 {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return -1
+	}
+	fields := bytes.Fields(statm)
+	if len(fields) < 2 {
+		return -1
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return float64(pages) * float64(os.Getpagesize())
+}
+
+func doSlabBenchmark(b *testing.B, memory uint64, huge bool) {
+	defer func(orig uint64) { hugeThreshold = orig }(hugeThreshold)
+	if huge {
+		hugeThreshold = 0
+	} else {
+		hugeThreshold = 1 << 63
+	}
+
+	p := Params{Time: 0, Upgrade: 0, Memory: memory}
+	h := NewHasher()
+	b.SetBytes(int64(p.MemoryBytes()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.BATTCrypt(xkcd, salt, p, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	// The heap slab is still held by h; the mapped one is already gone.
+	if rss := residentBytes(); rss >= 0 {
+		b.ReportMetric(rss/(1<<20), "MiB-rss")
+	}
+}
+
+func BenchmarkSlabHeapM8(b *testing.B)  { doSlabBenchmark(b, 8, false) }
+func BenchmarkSlabHeapM9(b *testing.B)  { doSlabBenchmark(b, 9, false) }
+func BenchmarkSlabHeapM10(b *testing.B) { doSlabBenchmark(b, 10, false) }
+func BenchmarkSlabHeapM11(b *testing.B) { doSlabBenchmark(b, 11, false) }
+func BenchmarkSlabHeapM12(b *testing.B) { doSlabBenchmark(b, 12, false) }
+func BenchmarkSlabHeapM13(b *testing.B) { doSlabBenchmark(b, 13, false) }
+func BenchmarkSlabHeapM14(b *testing.B) { doSlabBenchmark(b, 14, false) }
+func BenchmarkSlabHeapM15(b *testing.B) { doSlabBenchmark(b, 15, false) }
+func BenchmarkSlabHeapM16(b *testing.B) { doSlabBenchmark(b, 16, false) }
+
+func BenchmarkSlabMappedM8(b *testing.B)  { doSlabBenchmark(b, 8, true) }
+func BenchmarkSlabMappedM9(b *testing.B)  { doSlabBenchmark(b, 9, true) }
+func BenchmarkSlabMappedM10(b *testing.B) { doSlabBenchmark(b, 10, true) }
+func BenchmarkSlabMappedM11(b *testing.B) { doSlabBenchmark(b, 11, true) }
+func BenchmarkSlabMappedM12(b *testing.B) { doSlabBenchmark(b, 12, true) }
+func BenchmarkSlabMappedM13(b *testing.B) { doSlabBenchmark(b, 13, true) }
+func BenchmarkSlabMappedM14(b *testing.B) { doSlabBenchmark(b, 14, true) }
+func BenchmarkSlabMappedM15(b *testing.B) { doSlabBenchmark(b, 15, true) }
+func BenchmarkSlabMappedM16(b *testing.B) { doSlabBenchmark(b, 16, true) }
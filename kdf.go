@@ -0,0 +1,94 @@
+package battcrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrKDFExhausted is returned by a KDF stream after 2^64-1 blocks of output.
+var ErrKDFExhausted = errors.New("battcrypt: KDF output limit reached")
+
+// ErrKDFLength is returned by DeriveKeys for a negative key length.
+var ErrKDFLength = errors.New("battcrypt: negative KDF output length")
+
+// NewKDF returns a stream of key material of any length derived from
+// password and salt. Streams with different labels are independent, so one
+// password can safely produce several keys.
+//
+// The stream is the concatenation of HMAC-SHA-512(K, i || label) for
+// i = 1, 2, ..., where K is the result of BATTCrypt and i is a 64-bit
+// big-endian counter.
+func NewKDF(password, salt []byte, p Params, label string) (io.Reader, error) {
+	key, err := BATTCryptWithOptions(password, salt, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(key[:])
+	return newKDFReader(key, label), nil
+}
+
+// DeriveKeys derives one key of length bytes for each label. The password is
+// only hashed once, so this is cheaper than calling NewKDF for each label.
+func DeriveKeys(password, salt []byte, p Params, length int, labels ...string) ([][]byte, error) {
+	if length < 0 {
+		return nil, ErrKDFLength
+	}
+
+	key, err := BATTCryptWithOptions(password, salt, p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer wipe(key[:])
+
+	keys := make([][]byte, len(labels))
+	for i, label := range labels {
+		keys[i] = make([]byte, length)
+		if _, err = io.ReadFull(newKDFReader(key, label), keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+type kdfReader struct {
+	mac     hash.Hash
+	label   []byte
+	counter uint64
+	buf     [sha512.Size]byte
+	avail   []byte
+}
+
+func newKDFReader(key [64]byte, label string) *kdfReader {
+	return &kdfReader{
+		mac:   hmac.New(sha512.New, key[:]),
+		label: []byte(label),
+	}
+}
+
+func (r *kdfReader) Read(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(r.avail) == 0 {
+			if r.counter == ^uint64(0) {
+				return n, ErrKDFExhausted
+			}
+			r.counter++
+
+			var scratch [8]byte
+			binary.BigEndian.PutUint64(scratch[:], r.counter)
+			r.mac.Reset()
+			r.mac.Write(scratch[:])
+			r.mac.Write(r.label)
+			r.avail = r.mac.Sum(r.buf[:0])
+		}
+		c := copy(p, r.avail)
+		r.avail = r.avail[c:]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
@@ -0,0 +1,66 @@
+package battcrypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// The expected values were computed independently from the battcrypt
+// output for "password" and "salt" with t=0,u=0,m=0 (the first entry of
+// TestHashes) using HMAC-SHA-512 as documented on NewKDF.
+func TestKDF(t *testing.T) {
+	p := Params{Time: 0, Upgrade: 0, Memory: 0}
+
+	r, err := NewKDF([]byte("password"), []byte("salt"), p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// read in uneven pieces to cross block boundaries
+	var out []byte
+	for _, n := range []int{1, 62, 3, 34} {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, buf...)
+	}
+	if expected := "dafcd3053cc88b4c1057f5b6769a0b45a2f10d919adf396dc0f322bfe4c0078d7211d1cfaf4dfd7104fa646dd031ebae465e64cc1ac83655b90ce663a807e464ec2c51fa639d6802b3f4c4a078d28f720b682a1fddd926616ffc850d03e10c310f26b6ae"; hex.EncodeToString(out) != expected {
+		t.Errorf("%x != %s", out, expected)
+	}
+
+	keys, err := DeriveKeys([]byte("password"), []byte("salt"), p, 32, "aes", "mac", "iv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{
+		"36867473aedd86be24a55e0e1a269840bba5f0cd93e92ddd6afb4c927ff1ddf4",
+		"4aee61dccb3f710373f0d1e803ee804a36f8a75046831fe80cba97215fdf0d93",
+		"22636563a9002b0675fd87da7f0188b148ce62ee56b4227b434628f9e3732f14",
+	} {
+		if actual := hex.EncodeToString(keys[i]); actual != expected {
+			t.Errorf("key %d: %s != %s", i, actual, expected)
+		}
+	}
+}
+
+func TestKDFLabels(t *testing.T) {
+	keys, err := DeriveKeys(xkcd, salt, Params{}, 64, "a", "b", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(keys[0], keys[1]) {
+		t.Error("different labels gave the same key")
+	}
+	if !bytes.Equal(keys[0], keys[2]) {
+		t.Error("the same label gave different keys")
+	}
+
+	if _, err := DeriveKeys(xkcd, salt, Params{}, -1, "a"); err != ErrKDFLength {
+		t.Errorf("negative length: %v", err)
+	}
+	if _, err := DeriveKeys(xkcd, salt, Params{Memory: MaxMemory + 1}, 64, "a"); err != ErrCostRange {
+		t.Errorf("invalid costs: %v", err)
+	}
+}
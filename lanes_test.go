@@ -0,0 +1,168 @@
+package battcrypt
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+)
+
+func TestLanes(t *testing.T) {
+	var table = []struct {
+		Password, Salt, Hash string
+		Params               Params
+	}{
+		// generated by this package
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 2}, Hash: "a544b93de4570adb5e9cae7338dd3bee258a3ee345e84bbe3e368c4bdf4e640df8dbc2b1bd1fc525d525d40356f077e21313dcff3ca9a2b665e646788fecd4dd"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 2, Lanes: 1}, Hash: "a544b93de4570adb5e9cae7338dd3bee258a3ee345e84bbe3e368c4bdf4e640df8dbc2b1bd1fc525d525d40356f077e21313dcff3ca9a2b665e646788fecd4dd"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 2, Lanes: 2}, Hash: "b7c4eee3d3998bdd698ed581da30f43863bd99587b5664a9433eceaea75b1a8020bedc9419d536648cb5b4628475759d5c6464c183c444de50204074687a7475"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 2, Lanes: 4}, Hash: "ac14242ceb794d34ac9d217a8d5bb6159de4e6c3a75ca7dbd1366f72e490f949bd7de94b7b27771215f7eaac5058ba4cc1669ddefa6b2c633849613bbf5595e8"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 0, Memory: 3}, Hash: "0af7b529dd79e088a7c02a8c07131adc64ef90035d361be01cf466634fd2ace8e947f392808baf3798a11a6b56b7179cbe5ca4f20f991319eb3970e352e38088"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 0, Memory: 3, Lanes: 1}, Hash: "0af7b529dd79e088a7c02a8c07131adc64ef90035d361be01cf466634fd2ace8e947f392808baf3798a11a6b56b7179cbe5ca4f20f991319eb3970e352e38088"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 0, Memory: 3, Lanes: 2}, Hash: "0a783e98f7c1ab648c2227e29e3cece0ba5a12812c8062e4ad06a116d6e9d278b1b2db21c604a447d05c7885c16edffc9f52d95cfd1ab0c01de208e45bd5382d"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 0, Memory: 3, Lanes: 4}, Hash: "779bb07d1786d74e3c744673b523e10ace716104eeef4f8e1ddb7c1bfafa1efeb4e765fc2b42cbce3abebfae842cd595e912443611f719ca98e885154fa05957"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 2, Memory: 2, Mode: ModeHybrid}, Hash: "478e054c7b377e1ad2e4b00258d1b08dec71f4ba1f6eaf56d04558507db451e85ce497574bab9e3ffd69851dd8da79d8192e0536e2eda36abb0a27268e1adb95"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 2, Memory: 2, Mode: ModeHybrid, Lanes: 1}, Hash: "478e054c7b377e1ad2e4b00258d1b08dec71f4ba1f6eaf56d04558507db451e85ce497574bab9e3ffd69851dd8da79d8192e0536e2eda36abb0a27268e1adb95"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 2, Memory: 2, Mode: ModeHybrid, Lanes: 2}, Hash: "805f4fe00ebf2c0a0e4bc461da3dce132d2d1ff32d59f42cdcc4ff2b0223ada7d57d73f4075d8018077ebd0ca4c457754cec2c3a08093b860cc951c9e1a381a2"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 2, Memory: 2, Mode: ModeHybrid, Lanes: 4}, Hash: "609a564de38c4cf827cf2416526cd588eddc61903cfb53556e9a7bfdede195a6031514154d47fae274f0d31dcbcc5eca9f1ad362a22a72e1821e00edc66e4679"},
+	}
+
+	for _, test := range table {
+		key, err := BATTCryptWithOptions([]byte(test.Password), []byte(test.Salt), test.Params, nil)
+		if err != nil {
+			t.Errorf("%q %q %v: %v", test.Password, test.Salt, test.Params, err)
+			continue
+		}
+		if hex.EncodeToString(key[:]) != test.Hash {
+			t.Errorf("%q %q %v: %x != %s", test.Password, test.Salt, test.Params, key, test.Hash)
+		}
+	}
+}
+
+func TestLanesLimits(t *testing.T) {
+	if err := (Params{Memory: 2, Lanes: 4}).Validate(); err != nil {
+		t.Errorf("4 lanes of 4 blocks: %v", err)
+	}
+	if err := (Params{Memory: 0, Lanes: 2}).Validate(); err != ErrCostRange {
+		t.Errorf("2 lanes of 2 blocks: %v", err)
+	}
+	if err := (Params{Memory: 0, Lanes: 1}).Validate(); err != nil {
+		t.Errorf("1 lane: %v", err)
+	}
+	if err := (Params{Memory: 0, Lanes: 8}).Validate(); err != ErrCostRange {
+		t.Errorf("8 lanes of 4 blocks: %v", err)
+	}
+	if err := (Params{Memory: 10, Lanes: MaxLanes}).Validate(); err != nil {
+		t.Errorf("MaxLanes lanes: %v", err)
+	}
+	if err := (Params{Memory: 10, Lanes: MaxLanes * 2}).Validate(); err != ErrCostRange {
+		t.Errorf("too many lanes: %v", err)
+	}
+	// The rest of the memory would not be used.
+	if err := (Params{Memory: 2, Lanes: 3}).Validate(); err != ErrCostRange {
+		t.Errorf("3 lanes of 16 blocks: %v", err)
+	}
+}
+
+func TestLanesHasher(t *testing.T) {
+	h := NewHasher()
+	for _, p := range []Params{{Time: 1, Upgrade: 1, Memory: 2, Lanes: 4}, {Time: 0, Upgrade: 0, Memory: 1, Lanes: 2}, {Time: 1, Upgrade: 1, Memory: 2}} {
+		var calls [][2]uint64
+		key, err := h.BATTCrypt(xkcd, salt, p, &Options{Progress: func(completed, total uint64) {
+			calls = append(calls, [2]uint64{completed, total})
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := BATTCryptWithOptions(xkcd, salt, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%v: %x != %x", p, key, expected)
+		}
+		checkProgress(t, p.String(), calls, p.UpgradeIterations()*p.MemoryBlocks()*(p.MainIterations()+1))
+		checkWiped(t, p.String(), h)
+
+		strengthened, err := h.Strengthen(key, p, p.Upgrade+1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Upgrade++
+		if expected, _ = BATTCryptWithOptions(xkcd, salt, p, nil); strengthened != expected {
+			t.Errorf("%v: strengthened %x != %x", p, strengthened, expected)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := h.BATTCrypt(xkcd, salt, Params{Memory: 8, Lanes: 4}, &Options{Context: ctx}); err != context.Canceled {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
+
+func TestLanesCrossReferences(t *testing.T) {
+	const lanes, mem_size = 4, 64
+	var seen [lanes][lanes]int
+	for self := uint64(0); self < lanes; self++ {
+		for j := uint64(0); j < mem_size; j++ {
+			for r := uint64(0); r < 1<<10; r++ {
+				r := r<<54 | r*7919
+				other := refLane(r, j, self, lanes, mem_size)
+				k := r & (mem_size - 1)
+				if other != self && k/(mem_size/slices) == j/(mem_size/slices) {
+					t.Fatalf("lane %d block %d reads block %d of lane %d, which is in the slice being filled", self, j, k, other)
+				}
+				seen[self][other]++
+			}
+		}
+	}
+	for self := range seen {
+		for other, n := range seen[self] {
+			if n == 0 {
+				t.Errorf("lane %d never reads from lane %d", self, other)
+			}
+		}
+	}
+}
+
+func TestLanesCancel(t *testing.T) {
+	h := NewHasher()
+	p := Params{Time: 1, Memory: 6, Lanes: 4}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := h.BATTCrypt(xkcd, salt, p, &Options{Context: ctx, Progress: func(completed, total uint64) {
+		if completed*2 >= total {
+			cancel()
+		}
+	}})
+	if err != context.Canceled {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+	checkWiped(t, p.String(), h)
+
+	// The barrier must be usable again after a lane gave up.
+	key, err := h.BATTCrypt(xkcd, salt, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := BATTCryptWithOptions(xkcd, salt, p, nil); key != expected {
+		t.Errorf("after cancellation: %x != %x", key, expected)
+	}
+}
+
+func doLanesBenchmark(b *testing.B, lanes uint64) {
+	p := Params{Time: 0, Upgrade: 0, Memory: 10, Lanes: lanes}
+	h := NewHasher()
+	b.SetBytes(int64(p.MemoryBytes()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.BATTCrypt(xkcd, salt, p, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLanes1(b *testing.B) { doLanesBenchmark(b, 0) }
+func BenchmarkLanes2(b *testing.B) { doLanesBenchmark(b, 2) }
+func BenchmarkLanes4(b *testing.B) { doLanesBenchmark(b, 4) }
+func BenchmarkLanes8(b *testing.B) { doLanesBenchmark(b, 8) }
@@ -0,0 +1,92 @@
+package battcrypt
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// DefaultMemoryLimit is the initial value of the limit set by
+// SetMemoryLimit.
+const DefaultMemoryLimit = 1 << 30
+
+// ErrMemoryLimit is matched by errors.Is for every MemoryLimitError.
+var ErrMemoryLimit = errors.New("battcrypt: memory limit exceeded")
+
+// MemoryLimitError is returned, before any memory is allocated, when a hash
+// would need more memory than it is allowed.
+type MemoryLimitError struct {
+	// Requested is the number of bytes the hash needs, as reported by
+	// Params.MemoryBytes.
+	Requested uint64
+	// Limit is the limit that would have been exceeded.
+	Limit uint64
+	// InUse is the number of bytes used by other hashes in progress when
+	// the process-wide limit was exceeded.
+	InUse uint64
+}
+
+func (e MemoryLimitError) Error() string {
+	s := "battcrypt: hash needs " + strconv.FormatUint(e.Requested, 10) + " bytes of memory"
+	if e.InUse != 0 {
+		s += " with " + strconv.FormatUint(e.InUse, 10) + " bytes already in use"
+	}
+	return s + ", limit is " + strconv.FormatUint(e.Limit, 10) + " bytes"
+}
+
+// Is reports whether target is ErrMemoryLimit.
+func (e MemoryLimitError) Is(target error) bool {
+	return target == ErrMemoryLimit
+}
+
+var memory struct {
+	sync.Mutex
+	limit uint64
+	inUse uint64
+}
+
+func init() {
+	memory.limit = DefaultMemoryLimit
+}
+
+// SetMemoryLimit sets the most memory, in bytes, that all hashes in progress
+// in this process may use at once, and returns the previous limit. A hash
+// that would go over the limit fails immediately with a MemoryLimitError.
+// The limit starts at DefaultMemoryLimit.
+func SetMemoryLimit(limit uint64) (previous uint64) {
+	memory.Lock()
+	previous, memory.limit = memory.limit, limit
+	memory.Unlock()
+	return
+}
+
+// reserveMemory accounts for n bytes about to be allocated, after checking
+// them against callLimit (if not 0) and the process-wide limit.
+func reserveMemory(n, callLimit uint64) error {
+	if callLimit != 0 && n > callLimit {
+		return MemoryLimitError{Requested: n, Limit: callLimit}
+	}
+
+	memory.Lock()
+	defer memory.Unlock()
+
+	if n > memory.limit || memory.inUse > memory.limit-n {
+		return MemoryLimitError{Requested: n, Limit: memory.limit, InUse: memory.inUse}
+	}
+	memory.inUse += n
+	return nil
+}
+
+// releaseMemory undoes reserveMemory.
+func releaseMemory(n uint64) {
+	memory.Lock()
+	memory.inUse -= n
+	memory.Unlock()
+}
+
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,62 @@
+package battcrypt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	p := Params{Time: 0, Upgrade: 0, Memory: 2}
+	n := p.MemoryBytes()
+
+	if _, err := BATTCryptWithOptions(xkcd, salt, p, &Options{MemoryLimit: n}); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+	_, err := BATTCryptWithOptions(xkcd, salt, p, &Options{MemoryLimit: n - 1})
+	if err != (MemoryLimitError{Requested: n, Limit: n - 1}) {
+		t.Errorf("over the per-call limit: %v", err)
+	}
+	if !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("%v is not ErrMemoryLimit", err)
+	}
+	var mle MemoryLimitError
+	if !errors.As(err, &mle) || mle.Requested != n {
+		t.Errorf("%v does not report the requested size", err)
+	}
+
+	if _, err := StrengthenWithOptions([64]byte{}, p, 1, &Options{MemoryLimit: n - 1}); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Strengthen over the per-call limit: %v", err)
+	}
+}
+
+func TestProcessMemoryLimit(t *testing.T) {
+	defer SetMemoryLimit(SetMemoryLimit(Params{Time: 0, Upgrade: 0, Memory: 2}.MemoryBytes()))
+
+	if _, err := BATTCrypt(xkcd, salt, 0, 0, 2); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+	if _, err := BATTCrypt(xkcd, salt, 0, 0, 3); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("over the limit: %v", err)
+	}
+	if _, err := Strengthen([64]byte{}, 0, 0, 1, 3); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Strengthen over the limit: %v", err)
+	}
+
+	// Memory held by a hash in progress counts against the limit.
+	if err := reserveMemory(size, 0); err != nil {
+		t.Fatal(err)
+	}
+	_, err := BATTCrypt(xkcd, salt, 0, 0, 2)
+	releaseMemory(size)
+	if err != (MemoryLimitError{Requested: Params{Time: 0, Upgrade: 0, Memory: 2}.MemoryBytes(), Limit: Params{Time: 0, Upgrade: 0, Memory: 2}.MemoryBytes(), InUse: size}) {
+		t.Errorf("concurrent use: %v", err)
+	}
+}
+
+func TestMaxMemoryRejected(t *testing.T) {
+	// Without the limit, this would try to allocate 8 EiB.
+	h := &Hash{Version: Version, Params: Params{Time: 0, Upgrade: 0, Memory: MaxMemory}}
+	if err := CompareHashAndPassword(h.String(), xkcd); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package battcrypt
+
+import "syscall"
+
+// _MADV_DONTDUMP is missing from package syscall.
+const _MADV_DONTDUMP = 0x10
+
+// mapLocked maps n bytes of anonymous memory outside the Go heap, locks it
+// so it cannot be swapped out, and excludes it from core dumps.
+func mapLocked(n int) ([]byte, error) {
+	b, err := syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Mlock(b); err != nil {
+		// Usually RLIMIT_MEMLOCK is too low.
+		syscall.Munmap(b)
+		return nil, err
+	}
+	// Not supported before Linux 3.4; the memory is still locked.
+	_ = syscall.Madvise(b, _MADV_DONTDUMP)
+	return b, nil
+}
+
+// unmapLocked wipes and releases memory returned by mapLocked.
+func unmapLocked(b []byte) {
+	wipe(b)
+	syscall.Munlock(b)
+	syscall.Munmap(b)
+}
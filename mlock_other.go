@@ -0,0 +1,17 @@
+//go:build !linux
+
+package battcrypt
+
+import "errors"
+
+var errLockUnsupported = errors.New("battcrypt: locked memory is not supported on this system")
+
+// mapLocked always fails on systems other than Linux, so ordinary memory is
+// used instead.
+func mapLocked(n int) ([]byte, error) {
+	return nil, errLockUnsupported
+}
+
+func unmapLocked(b []byte) {
+	panic("battcrypt: unmapLocked called without mapLocked")
+}
@@ -0,0 +1,62 @@
+package battcrypt
+
+import (
+	"errors"
+	"testing"
+)
+
+func checkLockedHasher(t *testing.T, h *Hasher) {
+	for _, p := range []Params{{Time: 1, Upgrade: 1, Memory: 2}, {Time: 0, Upgrade: 0, Memory: 4}} {
+		key, err := h.BATTCrypt(xkcd, salt, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := BATTCrypt(xkcd, salt, p.Time, p.Upgrade, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%v: %x != %x", p, key, expected)
+		}
+		checkWiped(t, "locked "+p.String(), h)
+	}
+}
+
+func TestLockedHasher(t *testing.T) {
+	b, err := mapLocked(4096)
+	if err != nil {
+		t.Skipf("cannot lock memory: %v", err)
+	}
+	unmapLocked(b)
+
+	h := NewLockedHasher()
+	defer h.Close()
+
+	checkLockedHasher(t, h)
+	if !h.slabMapped || h.blowMap == nil {
+		t.Error("locked memory was not used")
+	}
+
+	key, err := BATTCryptWithOptions(xkcd, salt, Params{Time: 1, Upgrade: 1, Memory: 1}, &Options{LockMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := BATTCrypt(xkcd, salt, 1, 1, 1); key != expected {
+		t.Errorf("LockMemory: %x != %x", key, expected)
+	}
+}
+
+func TestLockedHasherFallback(t *testing.T) {
+	defer func(orig func(int) ([]byte, error)) { mapSlab = orig }(mapSlab)
+	mapSlab = func(int) ([]byte, error) {
+		return nil, errors.New("RLIMIT_MEMLOCK exceeded")
+	}
+
+	h := NewLockedHasher()
+	defer h.Close()
+
+	checkLockedHasher(t, h)
+	if h.slabMapped || h.blowMap != nil {
+		t.Error("failed mapping was used")
+	}
+}
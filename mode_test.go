@@ -0,0 +1,62 @@
+package battcrypt
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestModes(t *testing.T) {
+	var table = []struct {
+		Password, Salt, Hash string
+		Params               Params
+	}{
+		// generated by this package
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 0, Memory: 0, Mode: ModeIndependent}, Hash: "fa1dfbc10c5cef3dad126422599e1e1c3393aa0c518ca5aa0c918c6e65bc741469689873135a8556778e0b631871d7a39e412e2d4538a241d7d732a34ccda637"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 1, Mode: ModeIndependent}, Hash: "99b3ca257502d4388799fe200bf33dea96201247b5682bb541fa7638beddd7bc289d744aab57048781fd53785e84aa84fa8c9648aa8637fa024bc205ddb9ffcc"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 2, Memory: 3, Mode: ModeIndependent}, Hash: "55f1deecc0d48ee07480552c81b2e1dfa9c8ed60b67b7cce5a9023be214a64f03673abd1a9574320a3ce1f5b9cf0db438ce58d4c9e49968b0beb243b1c55dfb3"},
+		{Password: "", Salt: "", Params: Params{Time: 2, Upgrade: 0, Memory: 2, Mode: ModeIndependent}, Hash: "f87624a32b89fb7ce96d7e578551a0ad9aa8fc7051a2210503b9f0122fb8fd93ef72103a61b59c78a721be8b169374dce2ce8aa34d58c6001386b729511b0dfd"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 0, Upgrade: 0, Memory: 0, Mode: ModeHybrid}, Hash: "a41941db752bccb3aa0611a9410e2d57b7df04a33e24289a85dc42ae7fd73f706a36b247d8a2e923d09b16fd769b36d67feaa441e69ebbeaa9b5ae660c2a8096"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 1, Upgrade: 1, Memory: 1, Mode: ModeHybrid}, Hash: "d1bb482226d9e8f6f1938ff5564cd83676c4d90899762e79e07ef16e9c2fd2826bd400accfcfd5885a764aa49c2aa794a399dd92c5974ad87ffe41154a4e4e63"},
+		{Password: "password", Salt: "salt", Params: Params{Time: 2, Upgrade: 2, Memory: 3, Mode: ModeHybrid}, Hash: "3da0c7696cbff27df2bd91608b686fb52cdebccf3b6864479389aff21be8331ac06cdb9c01785eaabed49d781f941b078e8850bee7858a7b1224def3cdfe7475"},
+		{Password: "", Salt: "", Params: Params{Time: 2, Upgrade: 0, Memory: 2, Mode: ModeHybrid}, Hash: "98a177219e0a4202d8be0c785518a3dd42be8f35a59dc8421cd99165b9cb753bebd1869f9a78827efcc603cd4662a71109634dcd18c2ad620831f4a0d0db8ee3"},
+	}
+
+	for _, test := range table {
+		key, err := BATTCryptWithOptions([]byte(test.Password), []byte(test.Salt), test.Params, nil)
+		if err != nil {
+			t.Errorf("%q %q %v: %v", test.Password, test.Salt, test.Params, err)
+			continue
+		}
+		if hex.EncodeToString(key[:]) != test.Hash {
+			t.Errorf("%q %q %v: %x != %s", test.Password, test.Salt, test.Params, key, test.Hash)
+		}
+	}
+}
+
+func TestModeStrengthenAndEncoding(t *testing.T) {
+	for _, mode := range []Mode{ModeIndependent, ModeHybrid} {
+		p := Params{Time: 1, Upgrade: 0, Memory: 1, Mode: mode}
+		encoded, err := GenerateFromPassword(xkcd, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err = StrengthenHash(encoded, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := ParseHash(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Params.Mode != mode || h.Params.Upgrade != 2 {
+			t.Errorf("%s: wrong params %v", encoded, h.Params)
+		}
+		if err := CompareHashAndPassword(encoded, xkcd); err != nil {
+			t.Errorf("%s: %v", encoded, err)
+		}
+	}
+
+	if _, err := BATTCryptWithOptions(xkcd, salt, Params{Mode: ModeHybrid + 1}, nil); err != ErrModeInvalid {
+		t.Errorf("unknown mode: %v", err)
+	}
+}
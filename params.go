@@ -0,0 +1,218 @@
+package battcrypt
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrParamsSyntax is returned by ParseParams when its input is not of
+	// the form produced by Params.String.
+	ErrParamsSyntax = errors.New("battcrypt: costs must be of the form t=<time>,u=<upgrade>,m=<memory>[,a=<mode>][,p=<lanes>]")
+	// ErrModeInvalid is returned when Params.Mode is not a known Mode.
+	ErrModeInvalid = errors.New("battcrypt: unknown addressing mode")
+)
+
+// Mode selects how the main loop chooses which memory block to mix into
+// each block it updates.
+type Mode uint64
+
+const (
+	// ModeDependent picks blocks based on the data being hashed, which
+	// makes time-memory trade-offs expensive but lets an attacker who can
+	// observe cache timing learn about the password. It is the original
+	// battcrypt algorithm.
+	ModeDependent Mode = iota
+	// ModeIndependent picks blocks from a sequence that depends only on
+	// the costs, so the memory access pattern reveals nothing about the
+	// password.
+	ModeIndependent
+	// ModeHybrid uses ModeIndependent for the first pass over memory and
+	// ModeDependent for the rest, in the style of Argon2id.
+	ModeHybrid
+)
+
+// MaxLanes is the highest Params.Lanes accepted. Only powers of two up to
+// MaxLanes are accepted, so a lane count taken from runtime.NumCPU must be
+// rounded down to one.
+const MaxLanes = 128
+
+// slices is the number of parts each pass over memory is split into when
+// there is more than one lane. The lanes wait for each other at the end of
+// each slice, and may read any block of another lane that is not in the
+// slice being filled.
+const slices = 4
+
+// Params holds the three battcrypt costs, the addressing mode, and the
+// number of lanes. See the package documentation for the meaning of each
+// cost.
+type Params struct {
+	Time, Upgrade, Memory uint64
+	Mode                  Mode
+
+	// Lanes, if not 0, splits the memory into that many segments that
+	// are filled in parallel, each by its own goroutine. Four times in
+	// each pass over memory, the lanes wait for each other, and each lane
+	// mixes in blocks from the others as well as its own, so the lanes
+	// cannot be computed one after another in less memory. 0 and 1 both
+	// mean a single lane, which is the original battcrypt algorithm, and
+	// both are written out by String as 0 is, so ParseParams gives 0 for
+	// either. Otherwise, Lanes must be a power of two up to MaxLanes, so
+	// that it divides the number of memory blocks, which is always a power
+	// of two, and each lane must have at least four blocks. Other lane
+	// counts would leave blocks unused or need a slower way of picking
+	// them.
+	Lanes uint64
+}
+
+// layout checks p and returns the number of main loop passes and upgrade
+// iterations, the number of memory blocks in each lane, and the number of
+// lanes.
+func (p Params) layout() (t_cost_main, t_cost_upgrade, seg_size, lanes uint64, err error) {
+	if p.Mode > ModeHybrid {
+		return 0, 0, 0, 0, ErrModeInvalid
+	}
+	t_cost_main, t_cost_upgrade, mem_size, err := costs(p.Time, p.Upgrade, p.Memory)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	lanes = p.Lanes
+	if lanes == 0 {
+		lanes = 1
+	}
+	if lanes > MaxLanes || lanes&(lanes-1) != 0 || (lanes > 1 && mem_size/lanes < slices) {
+		return 0, 0, 0, 0, ErrCostRange
+	}
+	return t_cost_main, t_cost_upgrade, mem_size / lanes, lanes, nil
+}
+
+// Validate returns ErrCostRange if any cost is above MaxTime, MaxUpgrade, or
+// MaxMemory respectively, or the number of lanes is not allowed, or
+// ErrModeInvalid if Mode is unknown.
+func (p Params) Validate() error {
+	_, _, _, _, err := p.layout()
+	return err
+}
+
+// MainIterations returns the number of passes over memory made by each
+// upgrade iteration, or 0 if p is not valid.
+func (p Params) MainIterations() uint64 {
+	t_cost_main, _, _, _, _ := p.layout()
+	return t_cost_main
+}
+
+// UpgradeIterations returns the number of times the memory-hard function is
+// applied, or 0 if p is not valid.
+func (p Params) UpgradeIterations() uint64 {
+	_, t_cost_upgrade, _, _, _ := p.layout()
+	return t_cost_upgrade
+}
+
+// MemoryBlocks returns the number of 2 KiB memory blocks used by the main
+// loop, in all lanes together, or 0 if p is not valid.
+func (p Params) MemoryBlocks() uint64 {
+	_, _, seg_size, lanes, _ := p.layout()
+	return seg_size * lanes
+}
+
+// MemoryBytes returns the number of bytes allocated to compute a hash with
+// these costs, or 0 if p is not valid. This includes the memory blocks and
+// the block-sized working buffer of each lane.
+func (p Params) MemoryBytes() uint64 {
+	_, _, seg_size, lanes, err := p.layout()
+	if err != nil {
+		return 0
+	}
+	return size * (seg_size + 1) * lanes
+}
+
+// String returns the costs in the form used by encoded hashes, for example
+// "t=1,u=0,m=4". The mode is only included if it is not ModeDependent, and
+// the lanes only if there is more than one, as in "t=1,u=0,m=4,a=2,p=4", so
+// that hashes from earlier releases are unchanged.
+func (p Params) String() string {
+	var mode, lanes string
+	if p.Mode != ModeDependent {
+		mode = ",a=" + strconv.FormatUint(uint64(p.Mode), 10)
+	}
+	if p.Lanes > 1 {
+		lanes = ",p=" + strconv.FormatUint(p.Lanes, 10)
+	}
+	return "t=" + strconv.FormatUint(p.Time, 10) +
+		",u=" + strconv.FormatUint(p.Upgrade, 10) +
+		",m=" + strconv.FormatUint(p.Memory, 10) + mode + lanes
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Params) MarshalText() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *Params) UnmarshalText(text []byte) error {
+	q, err := ParseParams(string(text))
+	if err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+// ParseParams is the inverse of Params.String. Only the canonical form is
+// accepted so that every valid encoding has exactly one spelling. The costs
+// are checked with Validate.
+func ParseParams(s string) (p Params, err error) {
+	fields := strings.Split(s, ",")
+	if len(fields) < 3 || len(fields) > 5 {
+		return Params{}, ErrParamsSyntax
+	}
+	var ok [3]bool
+	p.Time, ok[0] = parseField(fields[0], "t=")
+	p.Upgrade, ok[1] = parseField(fields[1], "u=")
+	p.Memory, ok[2] = parseField(fields[2], "m=")
+	if !ok[0] || !ok[1] || !ok[2] {
+		return Params{}, ErrParamsSyntax
+	}
+	fields = fields[3:]
+	if len(fields) != 0 && strings.HasPrefix(fields[0], "a=") {
+		mode, ok := parseField(fields[0], "a=")
+		if !ok || mode == uint64(ModeDependent) {
+			// ModeDependent is never written out.
+			return Params{}, ErrParamsSyntax
+		}
+		p.Mode = Mode(mode)
+		fields = fields[1:]
+	}
+	if len(fields) != 0 && strings.HasPrefix(fields[0], "p=") {
+		lanes, ok := parseField(fields[0], "p=")
+		if !ok || lanes < 2 {
+			// A single lane is never written out.
+			return Params{}, ErrParamsSyntax
+		}
+		p.Lanes = lanes
+		fields = fields[1:]
+	}
+	if len(fields) != 0 {
+		return Params{}, ErrParamsSyntax
+	}
+	if err = p.Validate(); err != nil {
+		return Params{}, err
+	}
+	return p, nil
+}
+
+func parseField(s, prefix string) (uint64, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return 0, false
+	}
+	s = s[len(prefix):]
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || strconv.FormatUint(n, 10) != s {
+		return 0, false
+	}
+	return n, true
+}
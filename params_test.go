@@ -0,0 +1,109 @@
+package battcrypt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParamsDerived(t *testing.T) {
+	for _, test := range []struct {
+		Params                     Params
+		Main, Upgrade, Blocks, Mem uint64
+	}{
+		{Params{Time: 0, Upgrade: 0, Memory: 0}, 2, 1, 4, size * 5},
+		{Params{Time: 1, Upgrade: 1, Memory: 1}, 3, 2, 8, size * 9},
+		{Params{Time: 4, Upgrade: 4, Memory: 4}, 8, 6, 64, size * 65},
+		{Params{Time: 5, Upgrade: 6, Memory: 10}, 12, 12, 4096, size * 4097},
+		{Params{Time: MaxTime, Upgrade: MaxUpgrade, Memory: MaxMemory}, 2 << 31, 2 << 31, 4 << 50, size * (4<<50 + 1)},
+		{Params{Time: MaxTime + 1, Upgrade: 0, Memory: 0}, 0, 0, 0, 0},
+	} {
+		p := test.Params
+		if main, upgrade, blocks, mem := p.MainIterations(), p.UpgradeIterations(), p.MemoryBlocks(), p.MemoryBytes(); main != test.Main || upgrade != test.Upgrade || blocks != test.Blocks || mem != test.Mem {
+			t.Errorf("%v: got %d, %d, %d, %d; expected %d, %d, %d, %d", p, main, upgrade, blocks, mem, test.Main, test.Upgrade, test.Blocks, test.Mem)
+		}
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	if err := (Params{Time: MaxTime, Upgrade: MaxUpgrade, Memory: MaxMemory}).Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, p := range []Params{{Time: MaxTime + 1, Upgrade: 0, Memory: 0}, {Time: 0, Upgrade: MaxUpgrade + 1, Memory: 0}, {Time: 0, Upgrade: 0, Memory: MaxMemory + 1}} {
+		if err := p.Validate(); err != ErrCostRange {
+			t.Errorf("%v: %v", p, err)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	for _, test := range []struct {
+		Text   string
+		Params Params
+		Err    error
+	}{
+		{"t=1,u=0,m=4", Params{Time: 1, Upgrade: 0, Memory: 4}, nil},
+		{"t=62,u=63,m=50", Params{Time: 62, Upgrade: 63, Memory: 50}, nil},
+		{"t=63,u=0,m=0", Params{}, ErrCostRange},
+		{"t=1,u=0", Params{}, ErrParamsSyntax},
+		{"t=1,m=4,u=0", Params{}, ErrParamsSyntax},
+		{"t=01,u=0,m=4", Params{}, ErrParamsSyntax},
+		{"t=1, u=0, m=4", Params{}, ErrParamsSyntax},
+		{"t=-1,u=0,m=4", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=4,a=1", Params{Time: 1, Upgrade: 0, Memory: 4, Mode: ModeIndependent}, nil},
+		{"t=1,u=0,m=4,a=2", Params{Time: 1, Upgrade: 0, Memory: 4, Mode: ModeHybrid}, nil},
+		{"t=1,u=0,m=4,a=0", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=4,a=02", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=4,x=2", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=4,a=3", Params{}, ErrModeInvalid},
+		{"t=1,u=0,m=4,p=4", Params{Time: 1, Upgrade: 0, Memory: 4, Lanes: 4}, nil},
+		{"t=1,u=0,m=4,a=1,p=2", Params{Time: 1, Upgrade: 0, Memory: 4, Mode: ModeIndependent, Lanes: 2}, nil},
+		{"t=1,u=0,m=4,p=2,a=1", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=4,p=1", Params{}, ErrParamsSyntax},
+		{"t=1,u=0,m=0,p=5", Params{}, ErrCostRange},
+		{"t=1,u=0,m=4,p=3", Params{}, ErrCostRange},
+	} {
+		p, err := ParseParams(test.Text)
+		if p != test.Params || err != test.Err {
+			t.Errorf("ParseParams(%q) = %v, %v; expected %v, %v", test.Text, p, err, test.Params, test.Err)
+		}
+		if err == nil && p.String() != test.Text {
+			t.Errorf("%q != %q", p.String(), test.Text)
+		}
+	}
+}
+
+func TestParamsJSON(t *testing.T) {
+	var config struct {
+		Costs Params
+	}
+	if err := json.Unmarshal([]byte(`{"Costs":"t=2,u=1,m=8"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Costs != (Params{Time: 2, Upgrade: 1, Memory: 8}) {
+		t.Errorf("unexpected costs %v", config.Costs)
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"Costs":"t=2,u=1,m=8"}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+}
+
+func TestParamsTextRoundTrip(t *testing.T) {
+	for _, p := range []Params{{}, {Time: 1, Upgrade: 2, Memory: 3, Mode: ModeHybrid}, {Memory: 4, Lanes: 2}, {Memory: 10, Lanes: MaxLanes}} {
+		text, err := p.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var q Params
+		if err := q.UnmarshalText(text); err != nil || q != p {
+			t.Errorf("%v: unmarshaled %v, %v", p, q, err)
+		}
+	}
+	// 1 lane is written out as 0 lanes, which means the same.
+	if text, err := (Params{Memory: 2, Lanes: 1}).MarshalText(); err != nil || string(text) != "t=0,u=0,m=2" {
+		t.Errorf("1 lane: %s, %v", text, err)
+	}
+}
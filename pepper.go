@@ -0,0 +1,245 @@
+package battcrypt
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrPeppered is returned by CompareHashAndPassword for hashes that were
+// created by a PepperedHasher.
+var ErrPeppered = errors.New("battcrypt: hash is peppered and must be checked by a PepperedHasher")
+
+// ErrNoCurrentKey is returned by a KeyProvider that has no key to use for
+// new hashes.
+var ErrNoCurrentKey = errors.New("battcrypt: no current pepper")
+
+// UnknownKeyError is returned by a KeyProvider that does not have the
+// requested pepper. Its value is the key ID.
+type UnknownKeyError string
+
+func (e UnknownKeyError) Error() string {
+	return "battcrypt: unknown pepper key ID " + strconv.Quote(string(e))
+}
+
+// InvalidKeyIDError is returned when a key ID could not be recorded in an
+// encoded hash. Key IDs are 1 to 64 ASCII letters, digits, '.', '_', or '-'.
+type InvalidKeyIDError string
+
+func (e InvalidKeyIDError) Error() string {
+	return "battcrypt: invalid pepper key ID " + strconv.Quote(string(e))
+}
+
+func validKeyID(id string) bool {
+	if len(id) < 1 || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// A KeyProvider supplies the server-side secrets (peppers) used by a
+// PepperedHasher. Implementations must be safe for concurrent use.
+type KeyProvider interface {
+	// CurrentKey returns the pepper to use for new hashes and its ID.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the pepper with the given ID.
+	Key(id string) ([]byte, error)
+}
+
+// PepperedHasher hashes passwords that have first been run through
+// HMAC-SHA-512 keyed with a pepper, so that the encoded hashes cannot be
+// attacked offline without also knowing the pepper. The ID of the pepper is
+// recorded in each encoded hash.
+type PepperedHasher struct {
+	Keys   KeyProvider
+	Params Params
+}
+
+func pepper(key, password []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(password)
+	return mac.Sum(nil)
+}
+
+// GenerateFromPassword is like the package-level GenerateFromPassword, but
+// peppers the password with the current key from h.Keys.
+func (h *PepperedHasher) GenerateFromPassword(password []byte) (string, error) {
+	id, key, err := h.Keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	if !validKeyID(id) {
+		return "", InvalidKeyIDError(id)
+	}
+
+	encoded, err := newHash(h.Params, id)
+	if err != nil {
+		return "", err
+	}
+
+	peppered := pepper(key, password)
+	encoded.Key, err = BATTCryptWithOptions(peppered, encoded.Salt, h.Params, nil)
+	wipe(peppered)
+	if err != nil {
+		return "", err
+	}
+
+	return encoded.String(), nil
+}
+
+// CompareHashAndPassword is like the package-level CompareHashAndPassword,
+// but looks up the pepper recorded in the encoded hash. Hashes without a
+// key ID are checked without a pepper.
+//
+// If the password matches but the hash was not made with the current
+// pepper, or has an earlier version, the password is hashed again and the
+// new encoded hash is returned as rehashed. The caller should store it in
+// place of the old one. Otherwise rehashed is empty.
+func (h *PepperedHasher) CompareHashAndPassword(encoded string, password []byte) (rehashed string, err error) {
+	old, err := ParseHash(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	peppered := password
+	if old.KeyID != "" {
+		key, err := h.Keys.Key(old.KeyID)
+		if err != nil {
+			return "", err
+		}
+		peppered = pepper(key, password)
+		defer wipe(peppered)
+	}
+	if err = old.compare(peppered, nil); err != nil {
+		return "", err
+	}
+
+	current, _, err := h.Keys.CurrentKey()
+	if err != nil || (current == old.KeyID && old.Version == Version) {
+		// The password was correct, so a missing current key is not a
+		// reason to turn the user away.
+		return "", nil
+	}
+	return h.GenerateFromPassword(password)
+}
+
+// MemoryKeyProvider is a KeyProvider that keeps its peppers in memory.
+type MemoryKeyProvider struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// NewMemoryKeyProvider returns a MemoryKeyProvider whose current pepper is
+// key, with ID id.
+func NewMemoryKeyProvider(id string, key []byte) (*MemoryKeyProvider, error) {
+	p := &MemoryKeyProvider{}
+	if err := p.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Add makes a pepper available for checking existing hashes without using
+// it for new ones.
+func (p *MemoryKeyProvider) Add(id string, key []byte) error {
+	if !validKeyID(id) {
+		return InvalidKeyIDError(id)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys == nil {
+		p.keys = make(map[string][]byte)
+	}
+	p.keys[id] = append([]byte(nil), key...)
+	return nil
+}
+
+// Rotate adds a pepper and makes it the one used for new hashes. Hashes made
+// with earlier peppers can still be checked.
+func (p *MemoryKeyProvider) Rotate(id string, key []byte) error {
+	if err := p.Add(id, key); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.current = id
+	p.mu.Unlock()
+	return nil
+}
+
+// CurrentKey implements KeyProvider. The key returned is a copy, so the
+// caller may wipe it.
+func (p *MemoryKeyProvider) CurrentKey() (id string, key []byte, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.current == "" {
+		return "", nil, ErrNoCurrentKey
+	}
+	return p.current, append([]byte(nil), p.keys[p.current]...), nil
+}
+
+// Key implements KeyProvider. The key returned is a copy, so the caller may
+// wipe it.
+func (p *MemoryKeyProvider) Key(id string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, UnknownKeyError(id)
+	}
+	return append([]byte(nil), key...), nil
+}
+
+// LoadKeyFile reads peppers from a file into a MemoryKeyProvider. Each line
+// of the file holds a key ID and the base64-encoded pepper, separated by
+// white space. Blank lines and lines starting with '#' are ignored. The last
+// pepper in the file is the current one.
+func LoadKeyFile(path string) (*MemoryKeyProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &MemoryKeyProvider{}
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, errors.New("battcrypt: " + path + ":" + strconv.Itoa(line) + ": expected key ID and pepper")
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, errors.New("battcrypt: " + path + ":" + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if err = p.Rotate(fields[0], key); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
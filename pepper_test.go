@@ -0,0 +1,141 @@
+package battcrypt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPepperedHasher(t *testing.T) {
+	keys, err := NewMemoryKeyProvider("2024-01", []byte("first pepper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &PepperedHasher{Keys: keys, Params: Params{Time: 0, Upgrade: 0, Memory: 1}}
+
+	encoded, err := h.GenerateFromPassword(xkcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(encoded, ",k=2024-01$") {
+		t.Errorf("key ID missing from %q", encoded)
+	}
+
+	if rehashed, err := h.CompareHashAndPassword(encoded, xkcd); err != nil || rehashed != "" {
+		t.Errorf("current pepper: %q, %v", rehashed, err)
+	}
+	if _, err := h.CompareHashAndPassword(encoded, []byte("Tr0ub4dor&3")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := CompareHashAndPassword(encoded, xkcd); err != ErrPeppered {
+		t.Errorf("unpeppered compare: %v", err)
+	}
+
+	// Without the pepper, the stored key is not the hash of the password.
+	parsed, err := ParseHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.compare(xkcd, nil); err != ErrMismatchedHashAndPassword {
+		t.Errorf("compare without pepper: %v", err)
+	}
+
+	if err := keys.Rotate("2024-02", []byte("second pepper")); err != nil {
+		t.Fatal(err)
+	}
+	rehashed, err := h.CompareHashAndPassword(encoded, xkcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rehashed, ",k=2024-02$") {
+		t.Errorf("not re-peppered: %q", rehashed)
+	}
+	if again, err := h.CompareHashAndPassword(rehashed, xkcd); err != nil || again != "" {
+		t.Errorf("re-peppered hash: %q, %v", again, err)
+	}
+
+	// Hashes with an earlier version are replaced even if they use the
+	// current pepper.
+	parsed, err = ParseHash(rehashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.Version = 0
+	if rehashed, err := h.CompareHashAndPassword(parsed.String(), xkcd); err != nil || !strings.HasPrefix(rehashed, "$battcrypt$v=1$") {
+		t.Errorf("version 0 hash: %q, %v", rehashed, err)
+	}
+
+	// Hashes from before peppers were introduced are upgraded too.
+	plain, err := GenerateFromPassword(xkcd, h.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehashed, err := h.CompareHashAndPassword(plain, xkcd); err != nil || !strings.Contains(rehashed, ",k=2024-02$") {
+		t.Errorf("unpeppered hash: %q, %v", rehashed, err)
+	}
+
+	unknown := strings.Replace(encoded, ",k=2024-01$", ",k=2023-12$", 1)
+	if _, err := h.CompareHashAndPassword(unknown, xkcd); err != UnknownKeyError("2023-12") {
+		t.Errorf("unknown key ID: %v", err)
+	}
+}
+
+func TestMemoryKeyProvider(t *testing.T) {
+	var p MemoryKeyProvider
+	if _, _, err := p.CurrentKey(); err != ErrNoCurrentKey {
+		t.Errorf("empty provider: %v", err)
+	}
+	if err := p.Add("not valid", nil); err != InvalidKeyIDError("not valid") {
+		t.Errorf("invalid key ID: %v", err)
+	}
+	if err := p.Add("old", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.CurrentKey(); err != ErrNoCurrentKey {
+		t.Errorf("added key became current: %v", err)
+	}
+	if key, err := p.Key("old"); err != nil || string(key) != "old" {
+		t.Errorf("Key: %q, %v", key, err)
+	}
+
+	// Changing a returned key must not change the provider's copy.
+	key, _ := p.Key("old")
+	wipe(key)
+	if key, err := p.Key("old"); err != nil || string(key) != "old" {
+		t.Errorf("Key after wiping a copy: %q, %v", key, err)
+	}
+	if err := p.Rotate("new", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	_, key, _ = p.CurrentKey()
+	wipe(key)
+	if _, key, err := p.CurrentKey(); err != nil || string(key) != "new" {
+		t.Errorf("CurrentKey after wiping a copy: %q, %v", key, err)
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peppers")
+	if err := os.WriteFile(path, []byte("# peppers, oldest first\nk1 b2xk\n\nk2\tbmV3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, key, err := p.CurrentKey(); id != "k2" || string(key) != "new" || err != nil {
+		t.Errorf("CurrentKey: %q, %q, %v", id, key, err)
+	}
+	if key, err := p.Key("k1"); string(key) != "old" || err != nil {
+		t.Errorf("Key: %q, %v", key, err)
+	}
+
+	if err := os.WriteFile(path, []byte("k1 b2xk extra\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(path); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+}
@@ -0,0 +1,57 @@
+package battcrypt
+
+import "testing"
+
+func checkProgress(t *testing.T, name string, calls [][2]uint64, total uint64) {
+	if len(calls) == 0 {
+		t.Errorf("%s: progress was never reported", name)
+		return
+	}
+	if len(calls) > progressSteps+1 {
+		t.Errorf("%s: progress was reported %d times", name, len(calls))
+	}
+	var last uint64
+	for _, c := range calls {
+		if c[1] != total {
+			t.Errorf("%s: total %d != %d", name, c[1], total)
+		}
+		if c[0] <= last || c[0] > total {
+			t.Errorf("%s: completed went from %d to %d", name, last, c[0])
+		}
+		last = c[0]
+	}
+	if last != total {
+		t.Errorf("%s: finished at %d of %d", name, last, total)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	for _, p := range []Params{{Time: 0, Upgrade: 0, Memory: 0}, {Time: 1, Upgrade: 1, Memory: 1}, {Time: 2, Upgrade: 3, Memory: 7}, {Time: 0, Upgrade: 0, Memory: 10}} {
+		var calls [][2]uint64
+		opts := &Options{Progress: func(completed, total uint64) {
+			calls = append(calls, [2]uint64{completed, total})
+		}}
+
+		key, err := BATTCryptWithOptions(xkcd, salt, p, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := BATTCrypt(xkcd, salt, p.Time, p.Upgrade, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != expected {
+			t.Errorf("%v: %x != %x", p, key, expected)
+		}
+		checkProgress(t, "BATTCrypt "+p.String(), calls, p.UpgradeIterations()*p.MemoryBlocks()*(p.MainIterations()+1))
+
+		calls = nil
+		upgraded := p
+		upgraded.Upgrade += 2
+		if _, err := StrengthenWithOptions(key, p, upgraded.Upgrade, opts); err != nil {
+			t.Fatal(err)
+		}
+		rounds := upgraded.UpgradeIterations() - p.UpgradeIterations()
+		checkProgress(t, "Strengthen "+p.String(), calls, rounds*p.MemoryBlocks()*(p.MainIterations()+1))
+	}
+}
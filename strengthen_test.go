@@ -0,0 +1,157 @@
+package battcrypt
+
+import (
+	"testing"
+	"testing/quick"
+)
+
+func TestStrengthenExhaustive(t *testing.T) {
+	for time := uint64(0); time <= 2; time++ {
+		for memory := uint64(0); memory <= 1; memory++ {
+			var keys [6][64]byte
+			for upgrade := range keys {
+				key, err := BATTCrypt(xkcd, salt, time, uint64(upgrade), memory)
+				if err != nil {
+					t.Fatal(err)
+				}
+				keys[upgrade] = key
+			}
+
+			for from := range keys {
+				for to := from; to < len(keys); to++ {
+					key, err := Strengthen(keys[from], time, uint64(from), uint64(to), memory)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if key != keys[to] {
+						t.Errorf("t=%d,m=%d: strengthening from u=%d to u=%d does not match", time, memory, from, to)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestStrengthenProperty(t *testing.T) {
+	f := func(password, salt []byte, time, memory, from, by uint8) bool {
+		p := Params{Time: uint64(time % 3), Upgrade: uint64(from % 4), Memory: uint64(memory % 3)}
+		to := p.Upgrade + uint64(by%3)
+
+		old, err := BATTCrypt(password, salt, p.Time, p.Upgrade, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strengthened, err := Strengthen(old, p.Time, p.Upgrade, to, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := BATTCrypt(password, salt, p.Time, to, p.Memory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strengthened == expected
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStrengthenInvalid(t *testing.T) {
+	if _, err := Strengthen([64]byte{}, 0, 2, 1, 0); err != ErrUpgradeInvalid {
+		t.Errorf("lower upgrade cost: %v", err)
+	}
+	if key, err := Strengthen([64]byte{1}, 0, 2, 2, 0); err != nil || key != ([64]byte{1}) {
+		t.Errorf("same upgrade cost: %x, %v", key, err)
+	}
+}
+
+func TestStrengthenHash(t *testing.T) {
+	encoded, err := GenerateFromPassword(xkcd, Params{Time: 1, Upgrade: 0, Memory: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strengthened, err := StrengthenHash(encoded, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := ParseHash(strengthened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Params.Upgrade != 3 {
+		t.Errorf("upgrade cost %d != 3", h.Params.Upgrade)
+	}
+	if err := CompareHashAndPassword(strengthened, xkcd); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExtraIterations(t *testing.T) {
+	// Earlier releases strengthened from u=0 (1 iteration) to u=1
+	// (2 iterations) by performing 2 more iterations, giving the same
+	// result as u=2 (3 iterations).
+	legacy, err := BATTCrypt([]byte("password"), []byte("salt"), 1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Hash{
+		Version:         0,
+		Params:          Params{Time: 1, Upgrade: 1, Memory: 1},
+		ExtraIterations: 1,
+		Salt:            []byte("salt"),
+		Key:             legacy,
+	}
+	encoded := h.String()
+	if expected := "$battcrypt$v=0$t=1,u=1,m=1,x=1$c2FsdA$"; encoded[:len(expected)] != expected {
+		t.Errorf("%q does not start with %q", encoded, expected)
+	}
+	if err := CompareHashAndPassword(encoded, []byte("password")); err != nil {
+		t.Error(err)
+	}
+
+	// Strengthening it again with the fixed Strengthen keeps the extra
+	// iteration.
+	strengthened, err := StrengthenHash(encoded, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(strengthened, []byte("password")); err != nil {
+		t.Error(err)
+	}
+
+	if h, err := ParseHash(strengthened); err != nil || h.Version != 0 || h.ExtraIterations != 1 {
+		t.Errorf("strengthened to %q", strengthened)
+	}
+
+	// Without x=, a version 0 hash is checked with up to u= extra
+	// iterations.
+	h.ExtraIterations = 0
+	if err := CompareHashAndPassword(h.String(), []byte("password")); err != nil {
+		t.Errorf("without extra iterations: %v", err)
+	}
+	if err := CompareHashAndPassword(h.String(), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("wrong password: %v", err)
+	}
+	for _, password := range []string{"password", "wrong"} {
+		var calls [][2]uint64
+		opts := &Options{Progress: func(completed, total uint64) {
+			calls = append(calls, [2]uint64{completed, total})
+		}}
+		CompareHashAndPasswordWithOptions(h.String(), []byte(password), opts)
+		checkProgress(t, "legacy "+password, calls, 3*h.Params.MemoryBlocks()*(h.Params.MainIterations()+1))
+	}
+	h.Params.Upgrade = 0
+	if err := CompareHashAndPassword(h.String(), []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("more extra iterations than upgrades: %v", err)
+	}
+
+	// Current hashes are never checked with extra iterations.
+	h.Version, h.Params.Upgrade = Version, 1
+	if err := CompareHashAndPassword(h.String(), []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("version %d: %v", Version, err)
+	}
+	h.ExtraIterations = 1
+	if _, err := ParseHash(h.String()); err != MalformedHashError("extra iterations") {
+		t.Errorf("version %d with x=: %v", Version, err)
+	}
+}
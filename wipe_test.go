@@ -0,0 +1,91 @@
+package battcrypt
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/BenLubar/battcrypt/blowfish"
+)
+
+// checkWiped fails if h holds anything left over from a computation.
+func checkWiped(t *testing.T, name string, h *Hasher) {
+	for i, b := range h.slab[:cap(h.slab)] {
+		if b != 0 {
+			t.Errorf("%s: working memory not wiped at offset %d", name, i)
+			break
+		}
+	}
+	checkLaneWiped(t, name, &h.lane)
+	for i, l := range h.lanes {
+		checkLaneWiped(t, name+" lane "+strconv.Itoa(i+1), l)
+	}
+}
+
+func checkLaneWiped(t *testing.T, name string, l *lane) {
+	if l.key != ([64]byte{}) || l.scratch != ([8]byte{}) || l.indices != ([64]byte{}) {
+		t.Errorf("%s: key not wiped", name)
+	}
+	if *l.blow != (blowfish.Cipher{}) {
+		t.Errorf("%s: Blowfish key schedule not wiped", name)
+	}
+
+	// Reset does not clear the buffer of a partial block, so look at
+	// it directly.
+	if x := reflect.ValueOf(l.sha).Elem().FieldByName("x"); x.Kind() == reflect.Array {
+		for i := 0; i < x.Len(); i++ {
+			if x.Index(i).Uint() != 0 {
+				t.Errorf("%s: SHA-512 buffer not wiped", name)
+				break
+			}
+		}
+	} else {
+		t.Logf("%s: cannot inspect SHA-512 buffer of %T", name, l.sha)
+	}
+}
+
+func TestHasherWiped(t *testing.T) {
+	h := NewHasher()
+	p := Params{Time: 1, Upgrade: 1, Memory: 2}
+
+	key, err := h.BATTCrypt(xkcd, salt, p, &Options{AssociatedData: []byte("ad")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWiped(t, "BATTCrypt", h)
+
+	if _, err := h.Strengthen(key, p, 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkWiped(t, "Strengthen", h)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err = h.BATTCrypt(xkcd, salt, Params{Time: 0, Upgrade: 0, Memory: 8}, &Options{
+		Context: ctx,
+		Progress: func(completed, total uint64) {
+			if calls++; calls == 3 {
+				cancel()
+			}
+		},
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	checkWiped(t, "canceled BATTCrypt", h)
+}
+
+func TestPooledHasherWiped(t *testing.T) {
+	p := Params{Time: 0, Upgrade: 1, Memory: 3}
+	if _, err := BATTCrypt(xkcd, salt, p.Time, p.Upgrade, p.Memory); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pool may have dropped the Hasher, in which case there is
+	// nothing left to check.
+	if h, ok := hasherPools[p.Memory].Get().(*Hasher); ok {
+		checkWiped(t, "pooled", h)
+		putHasher(p.Memory, h)
+	}
+}